@@ -4,18 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/fatih/structs"
 	"github.com/gaowei-space/markdown-blog/internal/api"
 	"github.com/gaowei-space/markdown-blog/internal/bindata/assets"
 	"github.com/gaowei-space/markdown-blog/internal/bindata/views"
@@ -24,36 +21,35 @@ import (
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/middleware/accesslog"
 	"github.com/kataras/iris/v12/view"
-	"github.com/microcosm-cc/bluemonday"
-	"github.com/russross/blackfriday/v2"
 	"github.com/urfave/cli/v2"
 )
 
 var (
-	MdDir      string
-	Env        string
-	Title      string
-	Index      string
-	ICP        string
-	ISF        string
-	FDir       string
-	Copyright  int64
-	LayoutFile = "layouts/layout.html"
-	LogsDir    = "cache/logs/"
-	TocPrefix  = "[toc]"
-	IgnoreFile = []string{`favicon.ico`, `.DS_Store`, `.gitignore`, `README.md`}
-	IgnorePath = []string{`.git`, `assets`}
-	Cache      time.Duration
-	Analyzer   types.Analyzer
-	Gitalk     types.Gitalk
+	MdDir       string
+	Env         string
+	Title       string
+	Index       string
+	ICP         string
+	ISF         string
+	FDir        string
+	Copyright   int64
+	LayoutFile  = "layouts/layout.html"
+	LogsDir     = "cache/logs/"
+	TocPrefix   = "[toc]"
+	IgnoreFile  = []string{`favicon.ico`, `.DS_Store`, `.gitignore`, `README.md`, utils.SummaryFile}
+	IgnorePath  = []string{`.git`, `assets`}
+	StaticCache time.Duration // 图片等文件的浏览器缓存时间
+	Analyzer    types.Analyzer
+	Gitalk      types.Gitalk
+	Sanitizer   types.Sanitizer
 )
 
 // web服务器默认端口
 const DefaultPort = 5006
 
 func RunWeb(ctx *cli.Context) error {
-	go RunIndex(ctx)
 	initParams(ctx)
+	RunIndex(ctx)
 
 	app := iris.New()
 
@@ -63,46 +59,83 @@ func RunWeb(ctx *cli.Context) error {
 	app.OnErrorCode(iris.StatusNotFound, api.NotFound)
 	app.OnErrorCode(iris.StatusInternalServerError, api.InternalServerError)
 
-	setIndexAuto := false
-	if Index == "" {
-		setIndexAuto = true
-	}
+	indexAuto = Index == ""
 
-	app.Use(func(ctx iris.Context) {
-		activeNav := getActiveNav(ctx)
+	app.Favicon("./favicon.ico")
+	app.HandleDir("/static", getStatic())
+	app.Get("/search", pageMiddleware, searchHandler)
+	app.Get("/api/search", searchJSONHandler)
+	app.Get("/api/article/{f:path}", articleJSONHandler)
+	app.Get("/api/nav", navJSONHandler)
+	app.Get("/api/nav/{f:path}", navJSONHandler)
+	if Blog.Enable {
+		app.Get("/", pageMiddleware, postsHandler)
+	}
+	app.Get("/sitemap.xml", sitemapHandler)
+	app.Get("/robots.txt", robotsHandler)
+	app.Get("/feed.xml", rssHandler)
+	app.Get("/atom.xml", atomHandler)
+	app.Get("/feed.json", jsonFeedHandler)
+	if Blog.Enable {
+		app.Get("/posts", articleFirst, pageMiddleware, postsHandler)
+		app.Get("/archive", articleFirst, pageMiddleware, archiveHandler)
+	}
+	for kind, route := range taxonomyRoutes {
+		app.Get(route, articleFirst, pageMiddleware, taxonomyHandler(kind))
+		app.Get(route+"/{name:path}", articleFirst, pageMiddleware, taxonomyListHandler(kind))
+	}
+	app.Get("/{f:path}", pageMiddleware, articleHandler)
+	app.Get(fmt.Sprintf("/%s/{f:path}", FDir), serveFileHandler)
 
-		navs, firstNav := getNavs(activeNav)
+	app.Run(iris.Addr(":" + strconv.Itoa(parsePort(ctx))))
 
-		firstLink := utils.CustomURLEncode(strings.TrimPrefix(firstNav.Link, "/"))
-		if setIndexAuto && Index != firstLink {
-			Index = firstLink
-		}
+	return nil
+}
 
-		// 设置 Gitalk ID
-		Gitalk.Id = utils.MD5(activeNav)
+// pageMiddleware 为页面设置导航与布局等公共数据，静态文件与 JSON 接口不经过此处理
+func pageMiddleware(ctx iris.Context) {
+	activeNav := getActiveNav(ctx)
+	if imageRegexp.MatchString(activeNav) {
+		ctx.Next()
+		return
+	}
 
-		ctx.ViewData("Gitalk", Gitalk)
-		ctx.ViewData("Analyzer", Analyzer)
-		ctx.ViewData("Title", Title)
-		ctx.ViewData("Nav", navs)
-		ctx.ViewData("ICP", ICP)
-		ctx.ViewData("ISF", ISF)
-		ctx.ViewData("Copyright", Copyright)
-		ctx.ViewData("ActiveNav", activeNav)
-		ctx.ViewLayout(LayoutFile)
+	navs, navMore, firstNav := getNavs(activeNav)
 
-		ctx.Next()
-	})
+	firstLink := utils.CustomURLEncode(strings.TrimPrefix(firstNav.Link, "/"))
+	if indexAuto && Index != firstLink {
+		Index = firstLink
+	}
 
-	app.Favicon("./favicon.ico")
-	app.HandleDir("/static", getStatic())
-	app.Get("/search", searchHandler)
-	app.Get("/{f:path}", iris.Cache(Cache), articleHandler)
-	app.Get(fmt.Sprintf("/%s/{f:path}", FDir), serveFileHandler)
+	// 设置 Gitalk ID，按请求复制，不修改全局配置
+	gitalk := Gitalk
+	gitalk.Id = utils.MD5(activeNav)
 
-	app.Run(iris.Addr(":" + strconv.Itoa(parsePort(ctx))))
+	ctx.ViewData("Gitalk", gitalk)
+	ctx.ViewData("Analyzer", Analyzer)
+	ctx.ViewData("Title", Title)
+	ctx.ViewData("Nav", navs)
+	ctx.ViewData("NavMore", navMore)
+	ctx.ViewData("ICP", ICP)
+	ctx.ViewData("ISF", ISF)
+	ctx.ViewData("Copyright", Copyright)
+	ctx.ViewData("ActiveNav", activeNav)
+	ctx.ViewLayout(LayoutFile)
+
+	ctx.Next()
+}
 
-	return nil
+// articleFirst 标签、文章列表等生成的页面与文章或目录同名时显示文章或目录，
+// 使生成的页面不会覆盖已有的页面，也与 sitemap 和订阅中的链接一致
+func articleFirst(ctx iris.Context) {
+	f := strings.Trim(ctx.Path(), "/")
+	if _, _, ok := articleFile(f); !ok && !isDirPath(f) {
+		ctx.Next()
+		return
+	}
+	ctx.Params().Set("f", f)
+	ctx.HandlerIndex(0)
+	ctx.Do([]iris.Handler{pageMiddleware, articleHandler})
 }
 
 func getStatic() interface{} {
@@ -114,10 +147,11 @@ func getStatic() interface{} {
 }
 
 func getTmpl() *view.HTMLEngine {
+	// 模板目录中的短代码模板同样会被加载，需要注册短代码模板中可用的函数
 	if Env == "prod" {
-		return iris.HTML(views.AssetFile(), ".html").Reload(true)
+		return iris.HTML(views.AssetFile(), ".html").Reload(true).Funcs(shortcodeFuncs)
 	} else {
-		return iris.HTML("./web/views", ".html").Reload(true)
+		return iris.HTML("./web/views", ".html").Reload(true).Funcs(shortcodeFuncs)
 	}
 }
 
@@ -135,10 +169,21 @@ func initParams(ctx *cli.Context) {
 	ISF = ctx.String("isf")
 	Copyright = ctx.Int64("copyright")
 	FDir = ctx.String("fdir")
+	SourceDir = ctx.String("source-dir")
+	if SourceDir != "" {
+		SourceDir, _ = filepath.Abs(SourceDir)
+	}
+	setFormats(ctx.StringSlice("formats"))
+	setNavSort(ctx.String("nav-sort"))
+	NavPageSize = ctx.Int("nav-page-size")
+	PageSize = ctx.Int("page-size")
 
-	Cache = time.Minute * 0
+	StaticCache = time.Minute * 0
 	if Env == "prod" {
-		Cache = time.Minute * time.Duration(ctx.Int64("cache"))
+		StaticCache = time.Minute * time.Duration(ctx.Int64("static-cache"))
+	}
+	if ctx.IsSet("cache") {
+		log.Printf("The cache option is deprecated and has no effect, pages are cached by render-cache, use static-cache for images and files")
 	}
 
 	// 设置分析器
@@ -147,9 +192,33 @@ func initParams(ctx *cli.Context) {
 	// 设置Gitalk
 	Gitalk.SetGitalk(ctx.String("gitalk.client-id"), ctx.String("gitalk.client-secret"), ctx.String("gitalk.repo"), ctx.String("gitalk.owner"), ctx.StringSlice("gitalk.admin"), ctx.StringSlice("gitalk.labels"))
 
+	// 设置HTML清理策略
+	Sanitizer.SetSanitizer(ctx.String("sanitizer.preset"), ctx.StringSlice("sanitizer.elements"), ctx.StringSlice("sanitizer.attrs"), ctx.StringSlice("sanitizer.iframe-src"), ctx.StringSlice("sanitizer.overrides"))
+	Sanitizer.SetShortcode(ctx.StringSlice("sanitizer.shortcode-elements"), ctx.StringSlice("sanitizer.shortcode-attrs"), ctx.StringSlice("sanitizer.shortcode-iframe-src"))
+	initSanitizer()
+
+	// 设置参考文献
+	Bibliography.SetBibliography(ctx.String("bibliography.file"), ctx.StringSlice("bibliography.overrides"))
+
+	// 站点地址与 robots.txt
+	BaseURL = strings.TrimSpace(ctx.String("base-url"))
+	RobotsDisallow = ctx.StringSlice("robots.disallow")
+
+	// 订阅
+	FeedLimit = ctx.Int("feed.limit")
+	FeedFull = ctx.Bool("feed.full")
+
+	// 设置博客模式
+	Blog.SetBlog(ctx.Bool("blog.enable"), ctx.StringSlice("blog.post-dirs"))
+
+	// 文章渲染缓存
+	renderVersion = utils.MD5(fmt.Sprintf("%s|%+v|%+v", buildVersion(), Sanitizer, Bibliography))
+	articleCache = newRenderCache(ctx.Int("render-cache"), ctx.String("render-cache-dir"))
+
 	// 忽略文件
 	IgnoreFile = append(IgnoreFile, ctx.StringSlice("ignore-file")...)
 	IgnorePath = append(IgnorePath, FDir)
+	IgnorePath = append(IgnorePath, ShortcodeDir)
 	IgnorePath = append(IgnorePath, ctx.StringSlice("ignore-path")...)
 }
 
@@ -189,132 +258,190 @@ func parsePort(ctx *cli.Context) int {
 	return port
 }
 
-func getNavs(activeNav string) ([]map[string]interface{}, utils.Node) {
-	var option utils.Option
-	option.RootPath = []string{MdDir}
-	option.SubFlag = true
-	option.IgnorePath = IgnorePath
-	option.IgnoreFile = IgnoreFile
-	tree, _ := utils.Explorer(option)
-
-	navs := make([]map[string]interface{}, 0)
-	for _, v := range tree.Children {
-		for _, item := range v.Children {
-			searchActiveNav(item, activeNav)
-			navs = append(navs, structs.Map(item))
-		}
-	}
-
-	firstNav := getFirstNav(*tree.Children[0])
-
-	return navs, firstNav
-}
-
-func searchActiveNav(node *utils.Node, activeNav string) {
-	link_str, _ := url.QueryUnescape(node.Link)
-	if !node.IsDir && strings.TrimPrefix(link_str, "/") == activeNav {
-		node.Active = "active"
-		return
-	}
-	if len(node.Children) > 0 {
-		for _, v := range node.Children {
-			searchActiveNav(v, activeNav)
-		}
+// getNavs 返回当前请求的导航、根目录未显示的条目数量以及第一篇文章
+func getNavs(activeNav string) ([]*utils.Node, int, utils.Node) {
+	tree := getTree()
+	if len(tree.Children) == 0 {
+		return nil, 0, utils.Node{}
 	}
+	root := navView(tree.Children[0], activeNav)
+	return root.Children, root.More, getFirstNav(*tree.Children[0])
 }
 
+// getFirstNav 返回导航中显示的第一篇文章，没有时返回空节点
 func getFirstNav(node utils.Node) utils.Node {
-	if !node.IsDir {
+	if node.IsPage() {
 		return node
 	}
-	return getFirstNav(*node.Children[0])
+	for _, child := range node.Children {
+		if child.Hidden || !(child.IsDir || child.IsPage()) {
+			continue
+		}
+		if first := getFirstNav(*child); first.IsPage() && first.Link != "" {
+			return first
+		}
+	}
+	return utils.Node{}
 }
 
 func getActiveNav(ctx iris.Context) string {
 	f := ctx.Params().Get("f")
-	if f == "" {
+	// 博客模式的首页为文章列表，不对应导航中的文章
+	if f == "" && !(Blog.Enable && ctx.Path() == "/") {
 		f = Index
 	}
 	return f
 }
 
+// pathKey 将 MdDir 下的文件路径转换为访问路径，如 ops/deploy
+func pathKey(file string) string {
+	rel, err := filepath.Rel(MdDir, file)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+	return strings.TrimSuffix(rel, path.Ext(rel))
+}
+
 func serveFileHandler(ctx iris.Context) {
 	f := ctx.Params().Get("f")
 	file := MdDir + "/" + FDir + "/" + f
-	ctx.ServeFile(file)
+	serveImage(ctx, file)
 }
 
 func serveAssetsFileHandler(ctx iris.Context) {
 	f := ctx.Params().Get("f")
 	file := MdDir + "/" + f
-	ctx.ServeFile(file)
+	serveImage(ctx, file)
 }
 
 func articleHandler(ctx iris.Context) {
 	f := getActiveNav(ctx)
-	regx := regexp.MustCompile(`.*\.(jpeg|jpg|pjpg|gif|png|webp|svg)$`)
-	if regx.MatchString(f) {
+	if imageRegexp.MatchString(f) {
 		// log.Printf("serveAssetsFileHandler - %s", f)
 		serveAssetsFileHandler(ctx)
 		return
 	}
 
-	if utils.IsInSlice(IgnoreFile, f) {
+	if _, _, ok := dirIndexFile(f); !ok && isDirPath(f) {
+		dirHandler(ctx, f)
 		return
 	}
 
-	mdfile := MdDir + "/" + f + ".md"
-
-	_, err := os.Stat(mdfile)
-	if err != nil {
-		ctx.StatusCode(404)
-		ctx.Application().Logger().Errorf("Not Found '%s', Path is %s", mdfile, ctx.Path())
+	article, ok := loadArticle(ctx, f)
+	if !ok {
 		return
 	}
-
-	bytes, err := os.ReadFile(mdfile)
-	if err != nil {
-		ctx.StatusCode(500)
-		ctx.Application().Logger().Errorf("ReadFile Error '%s', Path is %s", mdfile, ctx.Path())
-		return
-	}
-	tmp := strings.Split(f, "/")
-	title := tmp[len(tmp)-1]
 	ctx.ViewData("Title", Title)
-	ctx.ViewData("ArticleTitle", title)
-	ctx.ViewData("Article", mdToHtml(bytes))
+	ctx.ViewData("ArticleTitle", article.Title)
+	ctx.ViewData("Article", article.HTML)
+	ctx.ViewData("Outline", article.Outline)
+	ctx.ViewData("Meta", article.Meta)
+	ctx.ViewData("Backlinks", article.Backlinks)
+	if _, _, ok := dirIndexFile(f); ok {
+		ctx.ViewData("FeedQuery", feedQuery("path", f+"/"))
+	}
+	ctx.ViewData("Tags", article.Tags)
+	ctx.ViewData("Categories", article.Categories)
+	ctx.ViewData("Breadcrumbs", article.Breadcrumbs)
+	ctx.ViewData("Prev", article.Prev)
+	ctx.ViewData("Next", article.Next)
 
 	ctx.View("index.html")
 }
 
-func mdToHtml(content []byte) template.HTML {
-	strs := string(content)
+// articleJSONHandler 以 JSON 格式输出文章
+func articleJSONHandler(ctx iris.Context) {
+	if article, ok := loadArticle(ctx, getActiveNav(ctx)); ok {
+		ctx.JSON(article)
+	}
+}
 
-	var htmlFlags blackfriday.HTMLFlags
+// Article 文章页面与 JSON 接口共用的文章数据
+type Article struct {
+	Title string `json:"title"`
+	Path  string `json:"path"`
+	*Rendered
+	Meta       ArticleMeta `json:"meta"`
+	Backlinks  []Backlink  `json:"backlinks"`
+	Tags       []TagLink   `json:"tags"`
+	Categories []TagLink   `json:"categories"`
+	ArticleNav
+}
 
-	if strings.HasPrefix(strs, TocPrefix) {
-		htmlFlags |= blackfriday.TOC
-		strs = strings.Replace(strs, TocPrefix, "<br/><br/>", 1)
+// loadArticle 读取并渲染文章，失败时设置响应状态码并返回 false
+func loadArticle(ctx iris.Context, f string) (*Article, bool) {
+	if utils.IsInSlice(IgnoreFile, f) {
+		return nil, false
 	}
 
-	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
-		Flags: htmlFlags,
-	})
+	// 目录以其中的 index 或 README 作为页面，文章中的相对链接按该文件解析
+	from := f
+	mdfile, format, ok := articleFile(f)
+	if !ok {
+		if mdfile, format, ok = dirIndexFile(f); ok {
+			from = path.Join(f, strings.TrimSuffix(path.Base(mdfile), path.Ext(mdfile)))
+		}
+	}
+	if !ok {
+		ctx.StatusCode(404)
+		ctx.Application().Logger().Errorf("Not Found '%s', Path is %s", MdDir+"/"+f, ctx.Path())
+		return nil, false
+	}
 
-	// fix windows \r\n
-	unix := strings.ReplaceAll(strs, "\r\n", "\n")
+	bytes, err := os.ReadFile(mdfile)
+	if err != nil {
+		ctx.StatusCode(500)
+		ctx.Application().Logger().Errorf("ReadFile Error '%s', Path is %s", mdfile, ctx.Path())
+		return nil, false
+	}
+	wiki := getWiki()
+	rendered := renderFile(mdfile, format, from, bytes, wiki)
 
-	unsafe := blackfriday.Run([]byte(unix), blackfriday.WithRenderer(renderer), blackfriday.WithExtensions(blackfriday.CommonExtensions))
+	title := rendered.Title
+	if title == "" {
+		title = utils.NameTitle(path.Base(f))
+	}
+	return &Article{
+		Title:      title,
+		Path:       f,
+		Rendered:   rendered,
+		Meta:       newArticleMeta(rendered.Stats, articleModTime(filepath.Clean(mdfile)), rendered.FrontMatter),
+		Backlinks:  getBacklinks(f, wiki),
+		Tags:       tagLinks(tagKind, rendered.FrontMatter.Tags),
+		Categories: tagLinks(categoryKind, rendered.FrontMatter.Categories),
+		ArticleNav: articleNav(f),
+	}, true
+}
 
-	// 创建bluemonday策略，只允许<span>标签及其style属性
-	p := bluemonday.UGCPolicy()
-	p.AllowElements("span")                  // 只允许<span>标签
-	p.AllowAttrs("style").OnElements("span") // 在<span>上允许使用style属性
+// renderFile 渲染文章，结果按内容与 wiki 索引缓存，from 为解析相对链接使用的访问路径
+func renderFile(mdfile string, format *ContentFormat, from string, content []byte, wiki *wikiIndex) *Rendered {
+	key := renderKey(contentSum(filepath.Clean(mdfile), content), wiki)
+	rendered, ok := articleCache.Get(filepath.Clean(mdfile), key)
+	if !ok {
+		rendered = format.Render(newRenderContext(mdfile, from, wiki), content)
+		articleCache.Put(filepath.Clean(mdfile), key, rendered)
+	}
+	return rendered
+}
 
-	// 使用自定义的bluemonday策略来清理HTML
-	html := p.SanitizeBytes(unsafe)
+// Backlink 反向链接，即链接到当前文章的其他文章
+type Backlink struct {
+	Title string `json:"title"`
+	Link  string `json:"link"`
+}
 
-	return template.HTML(string(html))
+func getBacklinks(f string, wiki *wikiIndex) []Backlink {
+	backlinks := make([]Backlink, 0)
+	if indexer == nil {
+		return backlinks
+	}
+	for _, src := range indexer.Backlinks(f) {
+		if node, ok := pageNode(wiki, src); ok {
+			backlinks = append(backlinks, Backlink{Title: node.ShowName, Link: node.Link})
+		}
+	}
+	return backlinks
 }
 
 func SubStr(str string, length int) string {
@@ -342,6 +469,7 @@ type SMetadata struct {
 	Path   string `json:"path"`
 	Title  string `json:"title"`
 	Md5sum string `json:"md5sum"`
+	ArticleMeta
 }
 
 type SDocument struct {
@@ -377,6 +505,33 @@ type SMessage struct {
 
 func searchHandler(ctx iris.Context) {
 	ctx.ViewData("Title", Title)
+	query := ctx.URLParam("keyword")
+	if data, ok := search(ctx); ok {
+		ctx.ViewData("Data", data)
+		ctx.ViewData("Keyword", query)
+		if data.Page > 1 {
+			ctx.ViewData("Prev", data.Page-1)
+		}
+		if data.PageCount > data.Page {
+			ctx.ViewData("Next", data.Page+1)
+		}
+	}
+	ctx.View("search.html")
+}
+
+// searchJSONHandler 以 JSON 格式输出搜索结果
+func searchJSONHandler(ctx iris.Context) {
+	data, ok := search(ctx)
+	msg := SMessage{State: ok, Message: "success", Data: data}
+	if !ok {
+		ctx.StatusCode(500)
+		msg.Message = "search failed"
+	}
+	ctx.JSON(msg)
+}
+
+// search 按请求参数 keyword、page、limit 查询搜索服务
+func search(ctx iris.Context) (SData, bool) {
 	query := ctx.URLParam("keyword")
 	page := 1
 	pageStr := ctx.URLParam("page")
@@ -403,15 +558,8 @@ func searchHandler(ctx iris.Context) {
 				msg := SMessage{}
 				if err := dec.Decode(&msg); err == nil {
 					if msg.Message == "success" {
-						ctx.ViewData("Data", msg.Data)
-						ctx.ViewData("Keyword", query)
-						if msg.Data.Page > 1 {
-							ctx.ViewData("Prev", msg.Data.Page-1)
-						}
-						if msg.Data.PageCount > msg.Data.Page {
-							ctx.ViewData("Next", msg.Data.Page+1)
-						}
 						log.Printf("Total: %d", msg.Data.Total)
+						return msg.Data, true
 					}
 				}
 			}
@@ -421,5 +569,5 @@ func searchHandler(ctx iris.Context) {
 	} else {
 		log.Printf("error: %s", err)
 	}
-	ctx.View("search.html")
+	return SData{}, false
 }
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// BibEntry BibTeX 文件中的一条文献
+type BibEntry struct {
+	Type   string            // 文献类型，如 article、book
+	Key    string            // 引用键
+	Fields map[string]string // 小写的字段名 -> 去掉花括号后的值
+}
+
+// bibFiles 已解析的 BibTeX 文件，文件修改后重新解析
+var bibFiles sync.Map
+
+type bibFile struct {
+	modTime time.Time
+	entries map[string]*BibEntry
+}
+
+// loadBibliography 读取并解析 BibTeX 文件，返回引用键 -> 文献
+func loadBibliography(file string) (map[string]*BibEntry, error) {
+	finfo, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := bibFiles.Load(file); ok && v.(*bibFile).modTime.Equal(finfo.ModTime()) {
+		return v.(*bibFile).entries, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseBibTeX(string(content))
+	bibFiles.Store(file, &bibFile{modTime: finfo.ModTime(), entries: entries})
+	return entries, nil
+}
+
+// parseBibTeX 解析 BibTeX，忽略 @comment、@preamble 与 @string 以及格式错误的条目
+func parseBibTeX(src string) map[string]*BibEntry {
+	entries := make(map[string]*BibEntry)
+	p := &bibParser{src: src}
+	for {
+		at := strings.IndexByte(p.src[p.pos:], '@')
+		if at < 0 {
+			break
+		}
+		p.pos += at + 1
+		typ := strings.ToLower(p.ident())
+		p.space()
+		if !p.eat('{') && !p.eat('(') {
+			continue
+		}
+		if typ == "comment" || typ == "preamble" || typ == "string" {
+			p.skipBlock()
+			continue
+		}
+		p.space()
+		key := strings.TrimSpace(p.until(",}"))
+		if key == "" || !p.eat(',') {
+			continue
+		}
+		entry := &BibEntry{Type: typ, Key: key, Fields: make(map[string]string)}
+		for {
+			p.space()
+			if p.eat('}') || p.eat(')') || p.pos >= len(p.src) {
+				break
+			}
+			name := strings.ToLower(p.ident())
+			p.space()
+			if name == "" || !p.eat('=') {
+				p.until(",}")
+				p.eat(',')
+				continue
+			}
+			entry.Fields[name] = cleanBibValue(p.value())
+			p.space()
+			p.eat(',')
+		}
+		entries[key] = entry
+	}
+	return entries
+}
+
+type bibParser struct {
+	src string
+	pos int
+}
+
+func (p *bibParser) space() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *bibParser) eat(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *bibParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if !(c == '_' || c == '-' || c == ':' || c == '.' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))) {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// until 读取到 stops 中任一字符之前
+func (p *bibParser) until(stops string) string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(stops, rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// skipBlock 跳过 @comment{...} 等条目的剩余部分
+func (p *bibParser) skipBlock() {
+	for depth := 1; p.pos < len(p.src) && depth > 0; p.pos++ {
+		switch p.src[p.pos] {
+		case '{', '(':
+			depth++
+		case '}', ')':
+			depth--
+		}
+	}
+}
+
+// value 读取字段值，支持 {...}、"..."、数字以及用 # 连接的多段
+func (p *bibParser) value() string {
+	var parts []string
+	for {
+		p.space()
+		switch {
+		case p.eat('{'):
+			start := p.pos
+			for depth := 1; p.pos < len(p.src); p.pos++ {
+				if p.src[p.pos] == '{' {
+					depth++
+				} else if p.src[p.pos] == '}' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			parts = append(parts, p.src[start:p.pos])
+			p.eat('}')
+		case p.eat('"'):
+			start := p.pos
+			for depth := 0; p.pos < len(p.src); p.pos++ {
+				c := p.src[p.pos]
+				if c == '{' {
+					depth++
+				} else if c == '}' {
+					depth--
+				} else if c == '"' && depth == 0 {
+					break
+				}
+			}
+			parts = append(parts, p.src[start:p.pos])
+			p.eat('"')
+		default:
+			parts = append(parts, p.ident())
+		}
+		p.space()
+		if !p.eat('#') {
+			return strings.Join(parts, "")
+		}
+	}
+}
+
+// 常见的 LaTeX 转义
+var latexReplacer = strings.NewReplacer(
+	`\&`, "&", `\%`, "%", `\$`, "$", `\_`, "_", `\#`, "#",
+	"---", "—", "--", "–", "~", " ", "``", "“", "''", "”",
+)
+
+// cleanBibValue 去掉保护大小写的花括号与常见的 LaTeX 转义，合并空白
+func cleanBibValue(v string) string {
+	v = strings.NewReplacer("{", "", "}", "").Replace(v)
+	v = latexReplacer.Replace(v)
+	return strings.Join(strings.Fields(v), " ")
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseBibTeX(t *testing.T) {
+	src := `
+@comment{ this {entry} is ignored }
+@string{ acm = "ACM" }
+@Article{knuth84,
+  Author  = {Knuth, Donald E.},
+  title   = "The {TeX}book",
+  journal = acm # { Press},
+  year    = 1984,
+  pages   = {1--10},
+  note    = {50\% off \& more},
+}
+@book(lamport94,
+  author = {Lamport, Leslie and others},
+  title  = {{LaTeX}: A Document
+            Preparation System}
+)
+@misc{, title = {no key}}
+@misc{broken title = {no comma}}
+@misc{empty,}
+`
+	entries := parseBibTeX(src)
+	if len(entries) != 3 {
+		t.Fatalf("parseBibTeX returned %d entries, want 3: %v", len(entries), entries)
+	}
+	tests := []struct {
+		key, typ, field, want string
+	}{
+		{"knuth84", "article", "author", "Knuth, Donald E."},
+		{"knuth84", "article", "title", "The TeXbook"},
+		{"knuth84", "article", "journal", "acm Press"},
+		{"knuth84", "article", "year", "1984"},
+		{"knuth84", "article", "pages", "1–10"},
+		{"knuth84", "article", "note", "50% off & more"},
+		{"lamport94", "book", "title", "LaTeX: A Document Preparation System"},
+		{"lamport94", "book", "author", "Lamport, Leslie and others"},
+	}
+	for _, tt := range tests {
+		e, ok := entries[tt.key]
+		if !ok {
+			t.Errorf("entry %q missing", tt.key)
+			continue
+		}
+		if e.Type != tt.typ {
+			t.Errorf("entry %q type = %q, want %q", tt.key, e.Type, tt.typ)
+		}
+		if got := e.Fields[tt.field]; got != tt.want {
+			t.Errorf("entry %q field %q = %q, want %q", tt.key, tt.field, got, tt.want)
+		}
+	}
+	if e, ok := entries["empty"]; !ok || len(e.Fields) != 0 {
+		t.Errorf("entry empty = %v, want no fields", e)
+	}
+}
+
+func TestFormatAuthors(t *testing.T) {
+	tests := []struct {
+		authors, want string
+	}{
+		{"", ""},
+		{"Knuth, Donald E.", "Donald E. Knuth"},
+		{"Alice Smith and Bob Jones", "Alice Smith and Bob Jones"},
+		{"Smith, Alice and Jones, Bob and Carol White", "Alice Smith, Bob Jones and Carol White"},
+		{"A and B and C and D", "A et al"},
+		{"Lamport, Leslie and others", "Leslie Lamport et al"},
+	}
+	for _, tt := range tests {
+		if got := formatAuthors(tt.authors); got != tt.want {
+			t.Errorf("formatAuthors(%q) = %q, want %q", tt.authors, got, tt.want)
+		}
+	}
+}
+
+// 文件修改后重新解析
+func TestLoadBibliographyReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "refs.bib")
+	if err := os.WriteFile(file, []byte(`@misc{a, title={One}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loadBibliography(file)
+	if err != nil || entries["a"] == nil || entries["a"].Fields["title"] != "One" {
+		t.Fatalf("loadBibliography = %v, %v", entries, err)
+	}
+	if err := os.WriteFile(file, []byte(`@misc{a, title={Two}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ = loadBibliography(file); entries["a"] == nil || entries["a"].Fields["title"] != "Two" {
+		t.Errorf("loadBibliography after change = %v, want title Two", entries["a"])
+	}
+	if _, err := loadBibliography(filepath.Join(t.TempDir(), "missing.bib")); err == nil {
+		t.Error("loadBibliography of a missing file: want error")
+	}
+}
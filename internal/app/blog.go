@@ -0,0 +1,121 @@
+package app
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gaowei-space/markdown-blog/internal/types"
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/kataras/iris/v12"
+)
+
+// Blog 博客模式配置
+var Blog types.Blog
+
+// ArchiveYear 归档页面中一年的文章
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	Months []ArchiveMonth `json:"months"`
+}
+
+// ArchiveMonth 归档页面中一个月的文章
+type ArchiveMonth struct {
+	Month time.Month  `json:"month"`
+	Posts []PostEntry `json:"posts"`
+}
+
+// postDate 文章的日期：front matter 中的 date，未设置时取索引中记录的修改时间
+func postDate(file string, fm utils.FrontMatter) time.Time {
+	if date, ok := utils.ParseDate(fm.Date); ok {
+		return date
+	}
+	return articleModTime(file)
+}
+
+// postUpdated 文章的更新时间：front matter 中的 updated、date 与索引中记录的修改时间中最晚的一个
+func postUpdated(file string, fm utils.FrontMatter) time.Time {
+	updated := articleModTime(file)
+	for _, s := range []string{fm.Updated, fm.Date} {
+		if date, ok := utils.ParseDate(s); ok && date.After(updated) {
+			updated = date
+		}
+	}
+	return updated
+}
+
+// isPost 判断访问路径对应的文章是否为博客文章，即位于 blog.post-dirs 中，未设置时全部文章都是
+func isPost(key string) bool {
+	if len(Blog.PostDirs) == 0 {
+		return true
+	}
+	for _, dir := range Blog.PostDirs {
+		dir = strings.Trim(path.Clean("/"+dir), "/")
+		if dir == "" || strings.HasPrefix(key, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// blogPosts 返回全部博客文章，按日期从新到旧排列。导航中隐藏的文章同样包括在内
+func blogPosts() []PostEntry {
+	return treePosts(isPost)
+}
+
+// treePosts 返回目录树中访问路径满足 match 的文章，按日期从新到旧排列
+func treePosts(match func(key string) bool) []PostEntry {
+	tree := getTree()
+	var files []string
+	var walk func(node *utils.Node)
+	walk = func(node *utils.Node) {
+		for _, child := range node.Children {
+			if child.IsDir {
+				walk(child)
+			} else if child.IsPage() && match(nodeKey(child)) {
+				files = append(files, child.Path)
+			}
+		}
+	}
+	for _, root := range tree.Children {
+		walk(root)
+	}
+	return postEntries(files)
+}
+
+// postsHandler 按日期从新到旧分页列出博客文章，开启博客模式时作为首页
+func postsHandler(ctx iris.Context) {
+	posts := blogPosts()
+	pager, start, end := paginate(len(posts), ctx.URLParamIntDefault("page", 1))
+	ctx.ViewData("Title", Title)
+	if ctx.Path() != "/" {
+		ctx.ViewData("ArticleTitle", "文章")
+	}
+	ctx.ViewData("Posts", posts[start:end])
+	ctx.ViewData("Pager", pager)
+	ctx.ViewData("PagePath", ctx.Path())
+	ctx.View("posts.html")
+}
+
+// archiveHandler 按年、月归档全部博客文章
+func archiveHandler(ctx iris.Context) {
+	posts := blogPosts()
+	var archive []ArchiveYear
+	for _, post := range posts {
+		year, month := post.Date.Year(), post.Date.Month()
+		if n := len(archive); n == 0 || archive[n-1].Year != year {
+			archive = append(archive, ArchiveYear{Year: year})
+		}
+		y := &archive[len(archive)-1]
+		if n := len(y.Months); n == 0 || y.Months[n-1].Month != month {
+			y.Months = append(y.Months, ArchiveMonth{Month: month})
+		}
+		m := &y.Months[len(y.Months)-1]
+		m.Posts = append(m.Posts, post)
+	}
+	ctx.ViewData("Title", Title)
+	ctx.ViewData("ArticleTitle", "归档")
+	ctx.ViewData("Total", len(posts))
+	ctx.ViewData("Archive", archive)
+	ctx.View("archive.html")
+}
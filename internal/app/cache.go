@@ -0,0 +1,208 @@
+package app
+
+import (
+	"container/list"
+	"encoding/gob"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+)
+
+// renderCache 文章渲染结果的 LRU 缓存。
+// 缓存以文章内容的 md5 为键，内容变化后自然失效；
+// 同时由 Indexer 根据文件变化事件主动清除，以释放内存并处理依赖其他文件的渲染结果
+type renderCache struct {
+	mu    sync.Mutex
+	size  int    // 内存中最多缓存的文章数，0 表示不缓存
+	dir   string // 持久化目录，为空时仅缓存在内存中
+	ll    *list.List
+	items map[string]*list.Element // 文件路径 -> 缓存项
+}
+
+// cacheEntry 一篇文章的缓存项，每篇文章只保留最新内容的渲染结果
+type cacheEntry struct {
+	Path     string // 文章文件路径
+	Key      string // 内容 md5 与渲染相关状态组成的键
+	Rendered *Rendered
+}
+
+// articleCache 全局的文章渲染缓存，由 initParams 初始化
+var articleCache = newRenderCache(0, "")
+
+// buildVersion 当前可执行文件的 md5，程序重新编译后持久化的缓存随之失效；
+// 无法读取可执行文件时使用启动时间，持久化的缓存只在本次运行中有效
+func buildVersion() string {
+	if exe, err := os.Executable(); err == nil {
+		if sum := md5sum(exe); sum != "" {
+			return sum
+		}
+	}
+	return strconv.FormatInt(time.Now().UnixNano(), 10)
+}
+
+// renderVersion 程序版本与影响渲染结果的配置摘要，任一变化后持久化的缓存随之失效
+var renderVersion string
+
+// contentSum 文章内容的 md5：文件修改时间与索引库中记录的一致时使用 Indexer 已计算的 md5，
+// 尚未索引或文件修改后索引服务还未处理时计算内容的 md5
+func contentSum(file string, content []byte) string {
+	if indexer != nil {
+		if doc, ok := indexer.Find(file); ok && doc != nil && doc.Md5sum != "" {
+			if finfo, err := os.Stat(file); err == nil && finfo.ModTime().Equal(doc.ModTime) {
+				return doc.Md5sum
+			}
+		}
+	}
+	return utils.MD5(string(content))
+}
+
+// renderKey 文章的缓存键，由内容 md5、页面列表与渲染配置组成
+func renderKey(sum string, wiki *wikiIndex) string {
+	return sum + ":" + wiki.version + ":" + renderVersion
+}
+
+func newRenderCache(size int, dir string) *renderCache {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			log.Printf("Create render cache dir %s error: %s", dir, err)
+			dir = ""
+		}
+	}
+	return &renderCache{
+		size:  size,
+		dir:   dir,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get 查找文章的渲染结果，内存未命中时从持久化目录读取
+func (c *renderCache) Get(path, key string) (*Rendered, bool) {
+	if c.size <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[path]; ok {
+		entry := el.Value.(*cacheEntry)
+		if entry.Key == key && !depsChanged(entry.Rendered.Deps) {
+			c.ll.MoveToFront(el)
+			return entry.Rendered, true
+		}
+		return nil, false
+	}
+
+	if entry, ok := c.load(path); ok && entry.Key == key && !depsChanged(entry.Rendered.Deps) {
+		c.add(entry)
+		return entry.Rendered, true
+	}
+	return nil, false
+}
+
+// Put 缓存文章的渲染结果
+func (c *renderCache) Put(path, key string, rendered *Rendered) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{Path: path, Key: key, Rendered: rendered}
+	c.add(entry)
+	c.save(entry)
+}
+
+// Invalidate 清除文章以及嵌入了该文件的其他文章的缓存
+func (c *renderCache) Invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for p, el := range c.items {
+		entry := el.Value.(*cacheEntry)
+		if _, ok := entry.Rendered.Deps[path]; ok || p == path {
+			c.ll.Remove(el)
+			delete(c.items, p)
+			if c.dir != "" {
+				os.Remove(c.file(p))
+			}
+		}
+	}
+	if c.dir != "" {
+		os.Remove(c.file(path))
+	}
+}
+
+// Purge 清除全部缓存，用于文章增删等影响其他文章渲染结果的变化
+func (c *renderCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	if c.dir != "" {
+		files, _ := filepath.Glob(filepath.Join(c.dir, "*.gob"))
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}
+}
+
+func (c *renderCache) add(entry *cacheEntry) {
+	if el, ok := c.items[entry.Path]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[entry.Path] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).Path)
+	}
+}
+
+func (c *renderCache) file(path string) string {
+	return filepath.Join(c.dir, utils.MD5(path)+".gob")
+}
+
+func (c *renderCache) load(path string) (*cacheEntry, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	f, err := os.Open(c.file(path))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	var entry cacheEntry
+	if err := gob.NewDecoder(f).Decode(&entry); err != nil || entry.Path != path {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *renderCache) save(entry *cacheEntry) {
+	if c.dir == "" {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		log.Printf("Save render cache %s error: %s", entry.Path, err)
+		return
+	}
+	err = gob.NewEncoder(tmp).Encode(entry)
+	tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.file(entry.Path))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("Save render cache %s error: %s", entry.Path, err)
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// Callout 提示块的类型
+type Callout struct {
+	Title string // 默认标题
+	Icon  string // Font Awesome 图标
+}
+
+// callouts 支持的提示块类型，兼容 GitHub 与 Obsidian 的写法
+var callouts = map[string]Callout{
+	"note":      {Title: "Note", Icon: "fa-info-circle"},
+	"info":      {Title: "Info", Icon: "fa-info-circle"},
+	"tip":       {Title: "Tip", Icon: "fa-lightbulb-o"},
+	"success":   {Title: "Success", Icon: "fa-check-circle"},
+	"important": {Title: "Important", Icon: "fa-exclamation-circle"},
+	"question":  {Title: "Question", Icon: "fa-question-circle"},
+	"warning":   {Title: "Warning", Icon: "fa-exclamation-triangle"},
+	"caution":   {Title: "Caution", Icon: "fa-fire"},
+	"danger":    {Title: "Danger", Icon: "fa-bolt"},
+	"bug":       {Title: "Bug", Icon: "fa-bug"},
+	"example":   {Title: "Example", Icon: "fa-list"},
+	"quote":     {Title: "Quote", Icon: "fa-quote-left"},
+}
+
+// 匹配引用块首行的 [!NOTE]、[!WARNING]- 自定义标题
+var calloutRegexp = regexp.MustCompile(`^\[!(\w+)\]([+-]?)[ \t]*([^\n]*)\n?`)
+
+// 匹配 :::warning 自定义标题 形式的容器起始行
+var containerRegexp = regexp.MustCompile(`^:{3,}[ \t]*(\w+)([+-]?)[ \t]*(.*)$`)
+
+// convertContainers 将 :::type ... ::: 容器转换为 > [!TYPE] 引用块，支持嵌套
+func convertContainers(src string) string {
+	var out strings.Builder
+	depth := 0
+	fence := ""
+	for _, line := range strings.SplitAfter(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" {
+			if m := containerRegexp.FindStringSubmatch(trimmed); m != nil {
+				if _, ok := callouts[strings.ToLower(m[1])]; ok {
+					header := fmt.Sprintf("> [!%s]%s %s", strings.ToUpper(m[1]), m[2], m[3])
+					out.WriteString(strings.Repeat("> ", depth) + strings.TrimSpace(header) + "\n")
+					depth++
+					continue
+				}
+			}
+			if depth > 0 && len(trimmed) >= 3 && strings.Trim(trimmed, ":") == "" {
+				depth--
+				continue
+			}
+			if f := fenceMarker(strings.TrimLeft(line, " ")); f != "" {
+				fence = f
+			}
+		} else if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			fence = ""
+		}
+		out.WriteString(strings.Repeat("> ", depth))
+		out.WriteString(line)
+	}
+	return out.String()
+}
+
+// separateQuotes 在被空行隔开的相邻引用块之间插入 HTML 注释，
+// 避免 blackfriday 将它们合并为同一个引用块
+func separateQuotes(src string) string {
+	return transformBlocks(src, func(text string) string {
+		lines := strings.SplitAfter(text, "\n")
+		var out strings.Builder
+		quoted, blank := false, false
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case trimmed == "":
+				blank = true
+			case strings.HasPrefix(trimmed, ">"):
+				if quoted && blank {
+					out.WriteString("<!-- -->\n\n")
+				}
+				quoted, blank = true, false
+			default:
+				if blank {
+					quoted = false
+				}
+				blank = false
+			}
+			out.WriteString(line)
+		}
+		return out.String()
+	})
+}
+
+// renderCallout 将以 [!TYPE] 开头的引用块渲染为提示块，
+// 返回 false 表示该引用块不是提示块
+func (r *articleRenderer) renderCallout(w io.Writer, node *blackfriday.Node, entering bool) bool {
+	if !entering {
+		tag, ok := r.ctx.callouts[node]
+		if ok {
+			fmt.Fprintf(w, "</%s>\n", tag)
+		}
+		return ok
+	}
+
+	para := node.FirstChild
+	if para == nil || para.Type != blackfriday.Paragraph || para.FirstChild == nil || para.FirstChild.Type != blackfriday.Text {
+		return false
+	}
+	text := para.FirstChild
+	m := calloutRegexp.FindSubmatch(text.Literal)
+	if m == nil {
+		return false
+	}
+	kind := strings.ToLower(string(m[1]))
+	callout, ok := callouts[kind]
+	if !ok {
+		return false
+	}
+	title := strings.TrimSpace(string(m[3]))
+	if title == "" {
+		title = callout.Title
+	}
+
+	text.Literal = text.Literal[len(m[0]):]
+	if len(text.Literal) == 0 {
+		// 标记行之后的文本可能被拆分为多个节点，去掉残留的换行
+		if next := text.Next; next != nil && next.Type == blackfriday.Text {
+			next.Literal = []byte(strings.TrimLeft(string(next.Literal), "\n"))
+		}
+		text.Unlink()
+		if para.FirstChild == nil {
+			para.Unlink()
+		}
+	}
+
+	class := "callout callout-" + kind
+	icon := fmt.Sprintf(`<i class="fa %s"></i> `, callout.Icon)
+	switch string(m[2]) {
+	case "":
+		r.ctx.callouts[node] = "div"
+		fmt.Fprintf(w, "<div class=\"%s\">\n<p class=\"callout-title\">%s%s</p>\n", class, icon, html.EscapeString(title))
+	default:
+		open := ""
+		if string(m[2]) == "+" {
+			open = " open"
+		}
+		r.ctx.callouts[node] = "details"
+		fmt.Fprintf(w, "<details class=\"%s\"%s>\n<summary class=\"callout-title\">%s%s</summary>\n", class, open, icon, html.EscapeString(title))
+	}
+	return true
+}
@@ -0,0 +1,476 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// chartPalette 数据系列依次使用的颜色
+var chartPalette = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// chartSpec chart 代码块的配置，内联的 CSV 数据写在 --- 之后
+type chartSpec struct {
+	Type   string       `yaml:"type"`  // line、bar 或 pie，默认为 line
+	Title  string       `yaml:"title"` // 图表标题
+	Src    string       `yaml:"src"`   // 引用的 CSV/TSV 文件，先相对当前文件所在目录，再相对 MdDir
+	X      string       `yaml:"x"`     // 作为横轴或饼图标签的列，默认为第一列
+	Y      chartColumns `yaml:"y"`     // 作为数据系列的列，默认为其他数值列；饼图只取第一列
+	Width  int          `yaml:"width"`
+	Height int          `yaml:"height"`
+}
+
+// chartColumns 列名列表，也可以写成逗号分隔的字符串
+type chartColumns []string
+
+func (c *chartColumns) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*c = splitList(value.Value)
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+// chartSeries 一个数据系列，缺失或无法解析的数值为 NaN
+type chartSeries struct {
+	Name   string
+	Values []float64
+}
+
+// chartData 图表的数据：横轴标签与各数据系列
+type chartData struct {
+	Labels []string
+	Series []chartSeries
+}
+
+// renderChart 将 chart 代码块渲染为内联的 SVG
+func (c *renderContext) renderChart(literal string) string {
+	spec, rows, err := c.parseChart(literal)
+	if err != nil {
+		return dataError("chart", err)
+	}
+	data, err := spec.data(rows)
+	if err != nil {
+		return dataError("chart", err)
+	}
+
+	var svg string
+	switch spec.Type {
+	case "", "line", "bar":
+		svg, err = spec.axisChart(data)
+	case "pie":
+		svg, err = spec.pieChart(data)
+	default:
+		err = fmt.Errorf("unknown chart type %q", spec.Type)
+	}
+	if err != nil {
+		return dataError("chart", err)
+	}
+	return `<div class="chart">` + svg + "</div>\n"
+}
+
+// parseChart 解析图表配置并读取数据
+func (c *renderContext) parseChart(literal string) (*chartSpec, [][]string, error) {
+	head, data := literal, ""
+	lines := strings.Split(literal, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "---" {
+			head, data = strings.Join(lines[:i], "\n"), strings.Join(lines[i+1:], "\n")
+			break
+		}
+	}
+	var spec chartSpec
+	if err := yaml.Unmarshal([]byte(head), &spec); err != nil {
+		return nil, nil, err
+	}
+	spec.Type = strings.ToLower(strings.TrimSpace(spec.Type))
+
+	comma := ','
+	switch {
+	case spec.Src != "":
+		file, err := c.resolveInclude(spec.Src, c.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", spec.Src, err)
+		}
+		c.addDep(file)
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = string(content)
+		if d, ok := dataDelimiters[strings.TrimPrefix(path.Ext(file), ".")]; ok {
+			comma = d
+		}
+	case strings.TrimSpace(data) == "":
+		return nil, nil, fmt.Errorf("no data")
+	default:
+		// 内联数据的首行含有制表符时按 TSV 解析
+		if first, _, _ := strings.Cut(strings.TrimLeft(data, "\n"), "\n"); strings.Contains(first, "\t") {
+			comma = '\t'
+		}
+	}
+	rows, err := parseTable(data, comma)
+	return &spec, rows, err
+}
+
+// data 按配置的列取出横轴标签与数据系列
+func (s *chartSpec) data(rows [][]string) (*chartData, error) {
+	header, body := rows[0], rows[1:]
+	if len(body) == 0 {
+		return nil, fmt.Errorf("no data rows")
+	}
+	column := func(name string) (int, error) {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("column %q not found", name)
+	}
+	cell := func(row []string, i int) string {
+		if i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	x := 0
+	if s.X != "" {
+		var err error
+		if x, err = column(s.X); err != nil {
+			return nil, err
+		}
+	}
+	var ys []int
+	for _, name := range s.Y {
+		i, err := column(name)
+		if err != nil {
+			return nil, err
+		}
+		ys = append(ys, i)
+	}
+	if len(ys) == 0 {
+		for i := range header {
+			if i == x {
+				continue
+			}
+			for _, row := range body {
+				if !math.IsNaN(parseNumber(cell(row, i))) {
+					ys = append(ys, i)
+					break
+				}
+			}
+		}
+	}
+	if len(ys) == 0 {
+		return nil, fmt.Errorf("no numeric columns")
+	}
+
+	d := &chartData{}
+	for _, row := range body {
+		d.Labels = append(d.Labels, cell(row, x))
+	}
+	for _, i := range ys {
+		series := chartSeries{Name: strings.TrimSpace(header[i])}
+		for _, row := range body {
+			series.Values = append(series.Values, parseNumber(cell(row, i)))
+		}
+		d.Series = append(d.Series, series)
+	}
+	return d, nil
+}
+
+// parseNumber 解析数值，忽略千分位、百分号与货币符号，无法解析时返回 NaN
+func parseNumber(s string) float64 {
+	s = strings.NewReplacer(",", "", "_", "", "%", "", "$", "", "¥", "", "€", "").Replace(strings.TrimSpace(s))
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(v, 0) {
+		return math.NaN()
+	}
+	return v
+}
+
+// size 图表的宽高，未设置时为 640x320
+func (s *chartSpec) size() (float64, float64) {
+	clamp := func(v, def, lo, hi int) float64 {
+		switch {
+		case v <= 0:
+			v = def
+		case v < lo:
+			v = lo
+		case v > hi:
+			v = hi
+		}
+		return float64(v)
+	}
+	return clamp(s.Width, 640, 200, 1600), clamp(s.Height, 320, 120, 1000)
+}
+
+// open 输出 svg 开始标签与标题
+func (s *chartSpec) open(buf *strings.Builder, class string, w, h float64) {
+	label := s.Title
+	if label == "" {
+		label = "chart"
+	}
+	fmt.Fprintf(buf, `<svg class="chart-svg chart-%s" viewBox="0 0 %s %s" width="%s" height="%s" role="img" aria-label="%s">`,
+		class, num(w), num(h), num(w), num(h), html.EscapeString(label))
+	if s.Title != "" {
+		fmt.Fprintf(buf, `<text x="%s" y="24" text-anchor="middle" font-size="15" fill="currentColor">%s</text>`, num(w/2), html.EscapeString(s.Title))
+	}
+}
+
+// axisChart 折线图或柱状图
+func (s *chartSpec) axisChart(d *chartData) (string, error) {
+	w, h := s.size()
+	lo, hi := 0.0, 0.0
+	for _, series := range d.Series {
+		for _, v := range series.Values {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	if lo == hi {
+		hi = lo + 1
+	}
+	ticks := niceTicks(lo, hi, 5)
+	if len(ticks) < 2 {
+		return "", fmt.Errorf("value range %g to %g cannot be plotted", lo, hi)
+	}
+	lo, hi = ticks[0], ticks[len(ticks)-1]
+
+	labelWidth := 0.0
+	for _, t := range ticks {
+		labelWidth = math.Max(labelWidth, textWidth(formatTick(t), 11))
+	}
+	top, bottom, left, right := 16.0, 32.0, labelWidth+14, 16.0
+	if s.Title != "" {
+		top = 44
+	}
+	if len(d.Series) > 1 {
+		bottom += 24
+	}
+	plotW, plotH := w-left-right, h-top-bottom
+	yPos := func(v float64) float64 { return top + plotH*(hi-v)/(hi-lo) }
+	band := plotW / float64(len(d.Labels))
+	xPos := func(i int) float64 { return left + band*(float64(i)+0.5) }
+
+	kind := "line"
+	if s.Type == "bar" {
+		kind = "bar"
+	}
+	var buf strings.Builder
+	s.open(&buf, kind, w, h)
+
+	// 网格线与纵轴刻度
+	for _, t := range ticks {
+		y := yPos(t)
+		fmt.Fprintf(&buf, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="currentColor" stroke-opacity="0.15"/>`, num(left), num(y), num(w-right), num(y))
+		fmt.Fprintf(&buf, `<text x="%s" y="%s" text-anchor="end" font-size="11" fill="currentColor">%s</text>`, num(left-6), num(y+4), formatTick(t))
+	}
+	base := yPos(math.Max(lo, math.Min(0, hi)))
+	fmt.Fprintf(&buf, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="currentColor" stroke-opacity="0.5"/>`, num(left), num(base), num(w-right), num(base))
+
+	// 横轴标签，放不下时间隔显示
+	maxLabel := 0.0
+	for _, label := range d.Labels {
+		maxLabel = math.Max(maxLabel, textWidth(label, 11))
+	}
+	step := int(math.Ceil((maxLabel + 8) / band))
+	if step < 1 {
+		step = 1
+	}
+	for i, label := range d.Labels {
+		if i%step == 0 {
+			fmt.Fprintf(&buf, `<text x="%s" y="%s" text-anchor="middle" font-size="11" fill="currentColor">%s</text>`, num(xPos(i)), num(top+plotH+18), html.EscapeString(label))
+		}
+	}
+
+	for j, series := range d.Series {
+		color := chartPalette[j%len(chartPalette)]
+		if kind == "bar" {
+			barW := band * 0.8 / float64(len(d.Series))
+			for i, v := range series.Values {
+				if math.IsNaN(v) {
+					continue
+				}
+				x := left + band*float64(i) + band*0.1 + barW*float64(j)
+				y0, y1 := yPos(0), yPos(v)
+				fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"><title>%s</title></rect>`,
+					num(x), num(math.Min(y0, y1)), num(math.Max(barW-1, 1)), num(math.Abs(y1-y0)), color, pointTitle(series.Name, d.Labels[i], v))
+			}
+			continue
+		}
+		// 缺失的数据把折线分为多段
+		var points []string
+		flush := func() {
+			if len(points) > 1 {
+				fmt.Fprintf(&buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), color)
+			}
+			points = points[:0]
+		}
+		for i, v := range series.Values {
+			if math.IsNaN(v) {
+				flush()
+				continue
+			}
+			points = append(points, num(xPos(i))+","+num(yPos(v)))
+		}
+		flush()
+		for i, v := range series.Values {
+			if !math.IsNaN(v) {
+				fmt.Fprintf(&buf, `<circle cx="%s" cy="%s" r="3" fill="%s"><title>%s</title></circle>`, num(xPos(i)), num(yPos(v)), color, pointTitle(series.Name, d.Labels[i], v))
+			}
+		}
+	}
+
+	// 多个系列时在底部显示图例
+	if len(d.Series) > 1 {
+		x := left
+		for j, series := range d.Series {
+			legendItem(&buf, x, h-14, chartPalette[j%len(chartPalette)], series.Name)
+			x += textWidth(series.Name, 12) + 32
+		}
+	}
+	buf.WriteString("</svg>")
+	return buf.String(), nil
+}
+
+// pieChart 饼图，只取第一个数据系列中的正数
+func (s *chartSpec) pieChart(d *chartData) (string, error) {
+	w, h := s.size()
+	series := d.Series[0]
+	total := 0.0
+	for _, v := range series.Values {
+		if v > 0 {
+			total += v
+		}
+	}
+	if total == 0 {
+		return "", fmt.Errorf("no positive values in column %q", series.Name)
+	}
+	if math.IsInf(total, 0) {
+		return "", fmt.Errorf("sum of column %q is too large", series.Name)
+	}
+
+	top := 16.0
+	if s.Title != "" {
+		top = 44
+	}
+	r := math.Min((h-top-16)/2, w/4)
+	cx, cy := 16+r, top+r
+
+	var buf strings.Builder
+	s.open(&buf, "pie", w, h)
+	angle := -math.Pi / 2
+	n := 0
+	for i, v := range series.Values {
+		if !(v > 0) {
+			continue
+		}
+		color := chartPalette[n%len(chartPalette)]
+		title := html.EscapeString(fmt.Sprintf("%s: %s (%.1f%%)", d.Labels[i], formatValue(v), v/total*100))
+		if v == total {
+			fmt.Fprintf(&buf, `<circle cx="%s" cy="%s" r="%s" fill="%s"><title>%s</title></circle>`, num(cx), num(cy), num(r), color, title)
+		} else {
+			end := angle + v/total*2*math.Pi
+			large := 0
+			if end-angle > math.Pi {
+				large = 1
+			}
+			fmt.Fprintf(&buf, `<path d="M %s %s L %s %s A %s %s 0 %d 1 %s %s Z" fill="%s" stroke="#ffffff" stroke-width="1"><title>%s</title></path>`,
+				num(cx), num(cy), num(cx+r*math.Cos(angle)), num(cy+r*math.Sin(angle)), num(r), num(r), large,
+				num(cx+r*math.Cos(end)), num(cy+r*math.Sin(end)), color, title)
+			angle = end
+		}
+		legendItem(&buf, cx+r+32, top+12+float64(n)*20, color, fmt.Sprintf("%s (%.1f%%)", d.Labels[i], v/total*100))
+		n++
+	}
+	buf.WriteString("</svg>")
+	return buf.String(), nil
+}
+
+// legendItem 图例中的一项：色块与名称，y 为文字基线
+func legendItem(buf *strings.Builder, x, y float64, color, name string) {
+	fmt.Fprintf(buf, `<rect x="%s" y="%s" width="10" height="10" fill="%s"/>`, num(x), num(y-9), color)
+	fmt.Fprintf(buf, `<text x="%s" y="%s" font-size="12" fill="currentColor">%s</text>`, num(x+14), num(y), html.EscapeString(name))
+}
+
+// pointTitle 数据点的提示文字
+func pointTitle(series, label string, v float64) string {
+	return html.EscapeString(fmt.Sprintf("%s · %s: %s", series, label, formatValue(v)))
+}
+
+// niceTicks 覆盖 [lo, hi] 的刻度，间隔取 1、2、5 乘以 10 的幂，范围超出浮点数的表示时返回空
+func niceTicks(lo, hi float64, count int) []float64 {
+	raw := (hi - lo) / float64(count)
+	if !(raw > 0) || math.IsInf(raw, 0) {
+		return nil
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * exp
+	switch f := raw / exp; {
+	case f <= 1:
+		step = exp
+	case f <= 2:
+		step = 2 * exp
+	case f <= 5:
+		step = 5 * exp
+	}
+	first, last := math.Floor(lo/step)*step, math.Ceil(hi/step)*step
+	if !(step > 0) || math.IsInf(first, 0) || math.IsInf(last+step, 0) {
+		return nil
+	}
+	var ticks []float64
+	for v := first; v <= last+step/2; v += step {
+		ticks = append(ticks, math.Round(v/step)*step)
+	}
+	return ticks
+}
+
+// formatValue 格式化数值，去掉浮点误差
+func formatValue(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+// formatTick 格式化刻度，较大的数值以 k、M 缩写
+func formatTick(v float64) string {
+	switch a := math.Abs(v); {
+	case a >= 1e9:
+		return formatValue(v/1e9) + "B"
+	case a >= 1e6:
+		return formatValue(v/1e6) + "M"
+	case a >= 1e4:
+		return formatValue(v/1e3) + "k"
+	}
+	return formatValue(v)
+}
+
+// num 格式化 SVG 坐标，保留一位小数
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+// textWidth 估算文字的显示宽度，中日韩文字按全角计算
+func textWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		if r >= 0x2e80 {
+			width += size
+		} else {
+			width += size * 0.6
+		}
+	}
+	return width
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNiceTicks(t *testing.T) {
+	tests := []struct {
+		lo, hi float64
+		want   []float64
+	}{
+		{0, 97, []float64{0, 20, 40, 60, 80, 100}},
+		{-3, 4, []float64{-4, -2, 0, 2, 4}},
+		{0, 1, []float64{0, 0.2, 0.4, 0.6, 0.8, 1}},
+	}
+	for _, tt := range tests {
+		got := niceTicks(tt.lo, tt.hi, 5)
+		if len(got) != len(tt.want) {
+			t.Errorf("niceTicks(%g, %g) = %v, want %v", tt.lo, tt.hi, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("niceTicks(%g, %g) = %v, want %v", tt.lo, tt.hi, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// 范围为空、溢出或过小时不返回刻度，且不能陷入死循环
+func TestNiceTicksDegenerate(t *testing.T) {
+	tests := []struct {
+		lo, hi float64
+	}{
+		{0, 0},
+		{1, 1},
+		{-1e308, 1e308},
+		{0, math.MaxFloat64},
+		{-math.MaxFloat64, 0},
+		{0, 5e-324},
+		{0, math.Inf(1)},
+		{math.NaN(), 1},
+	}
+	for _, tt := range tests {
+		if got := niceTicks(tt.lo, tt.hi, 5); len(got) != 0 {
+			t.Errorf("niceTicks(%g, %g) = %v, want none", tt.lo, tt.hi, got)
+		}
+	}
+}
+
+func TestAxisChartRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []float64
+		wantErr bool
+	}{
+		{"zeros", []float64{0, 0}, false},
+		{"missing", []float64{math.NaN(), math.NaN()}, false},
+		{"single", []float64{42}, false},
+		{"negative", []float64{-5, -1}, false},
+		{"tiny", []float64{1e-300, 3e-300}, false},
+		{"huge", []float64{1e300, 3e300}, false},
+		{"overflow", []float64{1e308, -1e308}, true},
+		{"max", []float64{math.MaxFloat64}, true},
+		{"denormal", []float64{5e-324}, true},
+	}
+	for _, tt := range tests {
+		for _, kind := range []string{"line", "bar"} {
+			labels := make([]string, len(tt.values))
+			for i := range labels {
+				labels[i] = string(rune('a' + i))
+			}
+			spec := &chartSpec{Type: kind}
+			data := &chartData{Labels: labels, Series: []chartSeries{{Name: "v", Values: tt.values}}}
+			svg, err := spec.axisChart(data)
+			switch {
+			case tt.wantErr && err == nil:
+				t.Errorf("%s %s: want error, got svg", tt.name, kind)
+			case !tt.wantErr && err != nil:
+				t.Errorf("%s %s: unexpected error %v", tt.name, kind, err)
+			case !tt.wantErr && (!strings.HasPrefix(svg, "<svg") || strings.Contains(svg, "NaN") || strings.Contains(svg, "Inf")):
+				t.Errorf("%s %s: invalid svg %q", tt.name, kind, svg)
+			}
+		}
+	}
+}
+
+func TestPieChartOverflow(t *testing.T) {
+	spec := &chartSpec{Type: "pie"}
+	data := &chartData{Labels: []string{"a", "b"}, Series: []chartSeries{{Name: "v", Values: []float64{math.MaxFloat64, math.MaxFloat64}}}}
+	if _, err := spec.pieChart(data); err == nil {
+		t.Error("pieChart with an overflowing total: want error")
+	}
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/types"
+	"github.com/russross/blackfriday/v2"
+)
+
+// Bibliography 参考文献配置
+var Bibliography types.Bibliography
+
+// 匹配 [@key]、[@key, p. 12]、[@key1; @key2] 形式的引用
+var citationRegexp = regexp.MustCompile(`\[(@[^\[\]\n]+)\]`)
+
+// 匹配引用中的一项，如 @key 或 @key, p. 12
+var citeItemRegexp = regexp.MustCompile(`^@([\w:.#$%&+?<>~/-]+)(?:\s*,\s*(.+))?$`)
+
+// citations 文章中引用的文献，按首次出现的顺序编号
+type citations struct {
+	entries map[string]*BibEntry
+	order   []*BibEntry
+	numbers map[string]int
+}
+
+// bibliographyFor 返回文章适用的 BibTeX 文件，目录配置优先，匹配最长的目录
+func bibliographyFor(f string) string {
+	file, matched := Bibliography.File, ""
+	for _, o := range Bibliography.Overrides {
+		dir, bib, ok := strings.Cut(o, ":")
+		dir = strings.Trim(strings.TrimSpace(dir), "/")
+		if !ok || dir == "" {
+			continue
+		}
+		if (f == dir || strings.HasPrefix(f, dir+"/")) && len(dir) > len(matched) {
+			file, matched = strings.TrimSpace(bib), dir
+		}
+	}
+	if file != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(MdDir, filepath.FromSlash(file))
+	}
+	return file
+}
+
+// renderCitations 将 [@key] 替换为编号的引用链接，未配置参考文献时保持原样
+func (c *renderContext) renderCitations(src string) string {
+	file := bibliographyFor(c.Path)
+	if file == "" || !citationRegexp.MatchString(src) {
+		return src
+	}
+	c.addDep(file)
+	entries, err := loadBibliography(file)
+	if err != nil {
+		log.Printf("Load bibliography %s error: %s", file, err)
+		return src
+	}
+	c.cites = &citations{entries: entries, numbers: make(map[string]int)}
+
+	return transformMarkdown(src, func(text string) string {
+		return replaceCitations(text, func(m string) string {
+			var items []string
+			for _, item := range strings.Split(citationRegexp.FindStringSubmatch(m)[1], ";") {
+				sub := citeItemRegexp.FindStringSubmatch(strings.TrimSpace(item))
+				if sub == nil {
+					return m
+				}
+				items = append(items, c.cites.cite(sub[1], sub[2]))
+			}
+			return `<span class="citation">[` + strings.Join(items, "; ") + `]</span>`
+		})
+	})
+}
+
+// replaceCitations 对 text 中的引用应用 fn，
+// 后接 ( 或 [ 的是链接文本，如 [@alice](https://github.com/alice)，保持原样
+func replaceCitations(text string, fn func(string) string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range citationRegexp.FindAllStringIndex(text, -1) {
+		if m[1] < len(text) && (text[m[1]] == '(' || text[m[1]] == '[') {
+			continue
+		}
+		out.WriteString(text[last:m[0]])
+		out.WriteString(fn(text[m[0]:m[1]]))
+		last = m[1]
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// cite 生成一项引用，locator 为页码等定位信息
+func (cs *citations) cite(key, locator string) string {
+	var out string
+	if entry, ok := cs.entries[key]; ok {
+		n, ok := cs.numbers[key]
+		if !ok {
+			cs.order = append(cs.order, entry)
+			n = len(cs.order)
+			cs.numbers[key] = n
+		}
+		out = fmt.Sprintf(`<a class="citation-ref" href="#%s">%d</a>`, referenceID(key), n)
+	} else {
+		out = fmt.Sprintf(`<span class="citation-missing">@%s</span>`, html.EscapeString(key))
+	}
+	if locator != "" {
+		out += ", " + html.EscapeString(locator)
+	}
+	return out
+}
+
+// referenceID 参考文献条目的 ID
+func referenceID(key string) string {
+	return "ref-" + blackfriday.SanitizedAnchorName(key)
+}
+
+// renderReferences 生成文中引用过的参考文献列表
+func (c *renderContext) renderReferences() string {
+	if c.cites == nil || len(c.cites.order) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString(`<div class="references"><hr /><p class="references-title">参考文献</p><ol>`)
+	for _, entry := range c.cites.order {
+		fmt.Fprintf(&buf, `<li id="%s">%s</li>`, referenceID(entry.Key), formatReference(entry))
+	}
+	buf.WriteString(`</ol></div>`)
+	return buf.String()
+}
+
+// formatReference 将文献格式化为 作者. 标题. 期刊, 卷(期), 页码. 出版社, 年份. 链接
+func formatReference(e *BibEntry) string {
+	field := func(name string) string {
+		return html.EscapeString(e.Fields[name])
+	}
+	var parts []string
+	if authors := formatAuthors(e.Fields["author"]); authors != "" {
+		parts = append(parts, html.EscapeString(authors))
+	} else if editors := formatAuthors(e.Fields["editor"]); editors != "" {
+		parts = append(parts, html.EscapeString(editors)+" (eds.)")
+	}
+	if title := field("title"); title != "" {
+		if e.Type == "book" {
+			title = "<em>" + title + "</em>"
+		}
+		parts = append(parts, title)
+	}
+
+	var source []string
+	for _, name := range []string{"journal", "booktitle", "school", "institution"} {
+		if v := field(name); v != "" {
+			source = append(source, "<em>"+v+"</em>")
+			break
+		}
+	}
+	if v := field("volume"); v != "" {
+		if n := field("number"); n != "" {
+			v += "(" + n + ")"
+		}
+		source = append(source, v)
+	}
+	if v := field("pages"); v != "" {
+		source = append(source, v)
+	}
+	if len(source) > 0 {
+		parts = append(parts, strings.Join(source, ", "))
+	}
+
+	var publish []string
+	for _, name := range []string{"publisher", "organization", "year"} {
+		if v := field(name); v != "" {
+			publish = append(publish, v)
+		}
+	}
+	if len(publish) > 0 {
+		parts = append(parts, strings.Join(publish, ", "))
+	}
+
+	ref := strings.Join(parts, ". ") + "."
+	if doi := e.Fields["doi"]; doi != "" {
+		href := "https://doi.org/" + strings.TrimPrefix(doi, "https://doi.org/")
+		ref += fmt.Sprintf(` <a href="%s">doi:%s</a>`, html.EscapeString(href), html.EscapeString(doi))
+	} else if u := e.Fields["url"]; u != "" {
+		ref += fmt.Sprintf(` <a href="%s">%s</a>`, html.EscapeString(u), html.EscapeString(u))
+	}
+	return ref
+}
+
+// formatAuthors 将 "Last, First and Last2, First2" 格式化为 "First Last, First2 Last2"，
+// 超过三位作者时只显示第一位
+func formatAuthors(authors string) string {
+	if authors == "" {
+		return ""
+	}
+	var names []string
+	others := false
+	for _, name := range strings.Split(authors, " and ") {
+		name = strings.TrimSpace(name)
+		if name == "others" {
+			others = true
+			continue
+		}
+		if last, first, ok := strings.Cut(name, ","); ok {
+			name = strings.TrimSpace(strings.TrimSpace(first) + " " + strings.TrimSpace(last))
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	switch {
+	case len(names) == 0:
+		return ""
+	case len(names) > 3 || others:
+		return names[0] + " et al"
+	case len(names) == 1:
+		return names[0]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+}
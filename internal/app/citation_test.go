@@ -0,0 +1,51 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gaowei-space/markdown-blog/internal/types"
+)
+
+func TestReplaceCitations(t *testing.T) {
+	mark := func(m string) string { return "<" + m + ">" }
+	tests := []struct {
+		text, want string
+	}{
+		{"see [@knuth84].", "see <[@knuth84]>."},
+		{"[@a, p. 12; @b]", "<[@a, p. 12; @b]>"},
+		{"[@alice](https://github.com/alice)", "[@alice](https://github.com/alice)"},
+		{"[@alice][gh]", "[@alice][gh]"},
+		{"[@a] and [@b](x)", "<[@a]> and [@b](x)"},
+		{"email@example.com [not a cite]", "email@example.com [not a cite]"},
+		{"[@a\n@b]", "[@a\n@b]"},
+	}
+	for _, tt := range tests {
+		if got := replaceCitations(tt.text, mark); got != tt.want {
+			t.Errorf("replaceCitations(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBibliographyFor(t *testing.T) {
+	oldBib, oldDir := Bibliography, MdDir
+	t.Cleanup(func() { Bibliography, MdDir = oldBib, oldDir })
+	MdDir = "md"
+	Bibliography = types.Bibliography{
+		File:      "refs.bib",
+		Overrides: []string{"papers:papers/refs.bib", "papers/old:/abs/old.bib", "invalid", ":empty.bib"},
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"guide/a", filepath.Join("md", "refs.bib")},
+		{"papers/a", filepath.Join("md", "papers", "refs.bib")},
+		{"papersx/a", filepath.Join("md", "refs.bib")},
+		{"papers/old/a", "/abs/old.bib"},
+	}
+	for _, tt := range tests {
+		if got := bibliographyFor(tt.path); got != tt.want {
+			t.Errorf("bibliographyFor(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
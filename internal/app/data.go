@@ -0,0 +1,132 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// dataDelimiters 数据文件的后缀或数据代码块的语言 -> 分隔符
+var dataDelimiters = map[string]rune{"csv": ',', "tsv": '\t'}
+
+// parseTable 解析 CSV/TSV 数据，首行为表头，各行的列数可以不同
+func parseTable(content string, comma rune) ([][]string, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\ufeff")))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty data")
+	}
+	return rows, nil
+}
+
+// tableHTML 将数据渲染为表格，点击表头可排序
+func tableHTML(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	cells := func(tag string, row []string) string {
+		var buf strings.Builder
+		buf.WriteString("<tr>")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = strings.TrimSpace(row[i])
+			}
+			buf.WriteString("<" + tag + ">" + html.EscapeString(cell) + "</" + tag + ">")
+		}
+		buf.WriteString("</tr>\n")
+		return buf.String()
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<div class=\"data-table-wrapper\"><table class=\"data-table\">\n<thead>\n")
+	buf.WriteString(cells("th", rows[0]))
+	buf.WriteString("</thead>\n<tbody>\n")
+	for _, row := range rows[1:] {
+		buf.WriteString(cells("td", row))
+	}
+	buf.WriteString("</tbody>\n</table></div>\n")
+	return buf.String()
+}
+
+// dataError 数据或图表无法渲染时显示的提示
+func dataError(name string, err error) string {
+	return fmt.Sprintf(`<div class="callout callout-caution"><p class="callout-title"><i class="fa %s"></i> Invalid %s</p><p>%s</p></div>
+`,
+		callouts["caution"].Icon, html.EscapeString(name), html.EscapeString(err.Error()))
+}
+
+// dataText 表格中可搜索的文本，每行一段
+func dataText(rows [][]string) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderData 将 .csv/.tsv 文件渲染为表格
+func (c *renderContext) renderData(content []byte) *Rendered {
+	ext := strings.TrimPrefix(path.Ext(c.File), ".")
+	rows, err := parseTable(string(content), dataDelimiters[ext])
+	if err != nil {
+		return &Rendered{HTML: template.HTML(dataError(ext, err)), Deps: c.deps}
+	}
+	return &Rendered{
+		HTML:  template.HTML(sanitize(c.Path, []byte(tableHTML(rows)))),
+		Stats: plainStats(dataText(rows)),
+		Deps:  c.deps,
+	}
+}
+
+// dataSource 返回 .csv/.tsv 文件供索引的内容
+func dataSource(comma rune) func([]byte) articleSource {
+	return func(content []byte) articleSource {
+		rows, err := parseTable(string(content), comma)
+		if err != nil {
+			return articleSource{}
+		}
+		text := dataText(rows)
+		return articleSource{Text: text, Stats: plainStats(text)}
+	}
+}
+
+// renderDataBlock 渲染 csv、tsv 与 chart 代码块，其他代码块返回 false
+func (r *articleRenderer) renderDataBlock(w io.Writer, node *blackfriday.Node) bool {
+	lang := ""
+	if fields := strings.Fields(string(node.Info)); len(fields) > 0 {
+		lang = strings.ToLower(fields[0])
+	}
+	var out string
+	switch lang {
+	case "csv", "tsv":
+		rows, err := parseTable(string(node.Literal), dataDelimiters[lang])
+		if err != nil {
+			out = dataError(lang, err)
+		} else {
+			out = tableHTML(rows)
+		}
+	case "chart":
+		out = r.ctx.renderChart(string(node.Literal))
+	default:
+		return false
+	}
+	io.WriteString(w, out)
+	return true
+}
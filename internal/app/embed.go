@@ -0,0 +1,245 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SourceDir 可嵌入到代码块中的源码目录，为空时只能嵌入 FDir 中的文件
+var SourceDir string
+
+// 匹配单独一行的 {{< embed "src/main.go#region" lines="10-20" lang="go" >}}
+var embedCodeRegexp = regexp.MustCompile(`(?m)^[ \t]*\{\{<\s*embed\s+(.*?)\s*/?>\}\}[ \t]*$`)
+
+// 匹配源码中的区域标记，如 // #region name、# region name、<!-- #region name -->
+var (
+	regionStartRegexp = regexp.MustCompile(`(?:#|//|--|/\*|<!--|;)\s*#?region\s+([\w.-]+)`)
+	regionEndRegexp   = regexp.MustCompile(`(?:#|//|--|/\*|<!--|;)\s*#?endregion\b`)
+)
+
+// 文件后缀与代码高亮语言不一致时的对应关系
+var codeLanguages = map[string]string{
+	"yml": "yaml",
+	"md":  "markdown",
+	"h":   "c",
+	"hpp": "cpp",
+	"cc":  "cpp",
+	"mjs": "javascript",
+	"tsx": "typescript",
+	"jsx": "javascript",
+}
+
+// expandEmbeds 将 embed 指令替换为包含源码文件内容的代码块
+func (c *renderContext) expandEmbeds(src string) string {
+	return transformBlocks(src, func(text string) string {
+		return embedCodeRegexp.ReplaceAllStringFunc(text, func(m string) string {
+			args := embedCodeRegexp.FindStringSubmatch(m)[1]
+			var target string
+			opts := make(map[string]string)
+			for _, a := range shortcodeArgRegexp.FindAllStringSubmatch(args, -1) {
+				v := a[2]
+				if s, err := strconv.Unquote(v); err == nil {
+					v = s
+				} else {
+					v = strings.Trim(v, `'`)
+				}
+				if a[1] == "" && target == "" {
+					target = v
+				} else {
+					opts[a[1]] = v
+				}
+			}
+			code, lang, err := c.embedCode(target, opts)
+			if err != nil {
+				return strings.TrimRight(includeError(target, err), "\n")
+			}
+			return codeFence(code, lang)
+		})
+	})
+}
+
+// embedCode 读取源码文件中指定的区域或行
+func (c *renderContext) embedCode(target string, opts map[string]string) (string, string, error) {
+	name, region, _ := strings.Cut(target, "#")
+	if r, ok := opts["region"]; ok {
+		region = r
+	}
+	file, err := resolveSource(name)
+	if err != nil {
+		return "", "", err
+	}
+	c.addDep(file)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"), "\n")
+
+	if region != "" {
+		if lines, err = extractRegion(lines, region); err != nil {
+			return "", "", err
+		}
+	}
+	if spec, ok := opts["lines"]; ok {
+		if lines, err = selectLines(lines, spec); err != nil {
+			return "", "", err
+		}
+	}
+
+	lang := opts["lang"]
+	if lang == "" {
+		lang = strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+		if l, ok := codeLanguages[lang]; ok {
+			lang = l
+		}
+	}
+	return strings.Join(dedent(lines), "\n"), lang, nil
+}
+
+// resolveSource 解析嵌入的文件路径，先在 SourceDir 中查找，再在 FDir 中查找，结果必须位于对应目录内
+func resolveSource(name string) (string, error) {
+	if clean := path.Clean(strings.TrimPrefix(name, "/")); clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("path is outside the source directory")
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	type candidate struct{ root, name string }
+	var candidates []candidate
+	if SourceDir != "" {
+		candidates = append(candidates, candidate{SourceDir, name})
+	}
+	public := filepath.Join(MdDir, FDir)
+	candidates = append(candidates, candidate{public, name})
+	if strings.HasPrefix(name, FDir+"/") {
+		candidates = append(candidates, candidate{public, strings.TrimPrefix(name, FDir+"/")})
+	}
+
+	for _, cand := range candidates {
+		file := filepath.Join(cand.root, filepath.FromSlash(cand.name))
+		// 符号链接指向的真实路径同样必须位于目录内
+		target, err := filepath.EvalSymlinks(file)
+		if err != nil {
+			continue
+		}
+		if root, err := filepath.EvalSymlinks(cand.root); err != nil || !inDir(root, target) {
+			return "", fmt.Errorf("path is outside the source directory")
+		}
+		if finfo, err := os.Stat(target); err == nil && !finfo.IsDir() {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("file not found")
+}
+
+// extractRegion 提取 #region name 与 #endregion 之间的行，不含其他区域标记
+func extractRegion(lines []string, name string) ([]string, error) {
+	var out []string
+	depth := 0
+	for _, line := range lines {
+		start := regionStartRegexp.FindStringSubmatch(line)
+		end := start == nil && regionEndRegexp.MatchString(line)
+		if depth == 0 {
+			if start != nil && start[1] == name {
+				depth = 1
+			}
+			continue
+		}
+		switch {
+		case start != nil:
+			depth++
+			continue
+		case end:
+			if depth--; depth == 0 {
+				return out, nil
+			}
+			continue
+		}
+		out = append(out, line)
+	}
+	if depth > 0 {
+		return out, nil
+	}
+	return nil, fmt.Errorf("region %q not found", name)
+}
+
+// selectLines 按 lines 参数选择行，如 10-20、5-、-8、3 或 1-3,7-9，行号从 1 开始
+func selectLines(lines []string, spec string) ([]string, error) {
+	var out []string
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		start, end := 1, len(lines)
+		var err error
+		if from = strings.TrimSpace(from); from != "" {
+			if start, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid lines %q", spec)
+			}
+		}
+		if !isRange {
+			end = start
+		} else if to = strings.TrimSpace(to); to != "" {
+			if end, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid lines %q", spec)
+			}
+		}
+		if start < 1 || start > end || start > len(lines) {
+			return nil, fmt.Errorf("lines %q out of range", part)
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		out = append(out, lines[start-1:end]...)
+	}
+	return out, nil
+}
+
+// dedent 去掉各行共同的缩进
+func dedent(lines []string) []string {
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		return lines
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.TrimPrefix(line, prefix)
+	}
+	return out
+}
+
+// codeFence 生成围栏代码块，围栏长度大于代码中最长的连续反引号
+func codeFence(code, lang string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		longest = 2
+	}
+	fence := strings.Repeat("`", longest+1)
+	return fence + lang + "\n" + code + "\n" + fence
+}
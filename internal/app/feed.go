@@ -0,0 +1,324 @@
+package app
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+)
+
+var (
+	FeedLimit = 20    // 订阅中的文章数量
+	FeedFull  = false // 订阅中包含文章全文，否则只包含摘要
+)
+
+// 匹配 HTML 中以 / 开头的站内链接与图片地址
+var rootLinkRegexp = regexp.MustCompile(`(\s(?:href|src))="/([^/"][^"]*)?"`)
+
+// 匹配响应式图片的 srcset 属性
+var srcsetRegexp = regexp.MustCompile(`(\ssrcset)="([^"]*)"`)
+
+// feed 订阅的内容，由 RSS、Atom 与 JSON Feed 共用
+type feed struct {
+	Title   string
+	Link    string // 订阅对应页面的完整地址
+	Self    string // 订阅本身的完整地址
+	Updated time.Time
+	Items   []feedItem
+}
+
+type feedItem struct {
+	Title      string
+	Link       string
+	Published  time.Time
+	Updated    time.Time
+	Summary    string
+	HTML       string // 全文或摘要的 HTML，链接为完整地址
+	Categories []string
+}
+
+// feedFilter 订阅的范围：?path= 为目录中的文章，?tag= 与 ?category= 为带有标签或分类的文章，
+// 默认为全部博客文章。返回文章、订阅的标题后缀与对应页面的路径
+func feedFilter(ctx iris.Context) ([]PostEntry, string, string) {
+	if tag := ctx.URLParam("tag"); tag != "" {
+		return taggedPosts(tagKind, tag), taxonomyTitles[tagKind] + "：" + tag, tagLinks(tagKind, []string{tag})[0].Link
+	}
+	if category := ctx.URLParam("category"); category != "" {
+		return taggedPosts(categoryKind, category), taxonomyTitles[categoryKind] + "：" + category, tagLinks(categoryKind, []string{category})[0].Link
+	}
+	dir := strings.Trim(ctx.URLParam("path"), "/")
+	if dir == "" {
+		return blogPosts(), "", "/"
+	}
+	title := dir
+	if node, ok := findDir(getTree(), dir); ok {
+		title = node.ShowName
+	}
+	posts := treePosts(func(key string) bool {
+		return strings.HasPrefix(key, dir+"/")
+	})
+	return posts, title, "/" + dir
+}
+
+// taggedPosts 带有标签或分类的文章
+func taggedPosts(kind, name string) []PostEntry {
+	if indexer == nil {
+		return nil
+	}
+	return postEntries(indexer.Tagged(kind, name))
+}
+
+// buildFeed 按更新时间从新到旧取最近的 FeedLimit 篇文章生成订阅内容
+func buildFeed(ctx iris.Context, self string) feed {
+	site := siteURL(ctx)
+	posts, suffix, page := feedFilter(ctx)
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Updated.After(posts[j].Updated)
+	})
+	if FeedLimit > 0 && len(posts) > FeedLimit {
+		posts = posts[:FeedLimit]
+	}
+
+	f := feed{Title: Title, Link: absURL(site, page), Self: site + self}
+	if suffix != "" {
+		f.Title = suffix + " - " + Title
+	}
+	if query := ctx.Request().URL.RawQuery; query != "" {
+		f.Self += "?" + query
+	}
+	wiki := getWiki()
+	for _, post := range posts {
+		link, _ := url.PathUnescape(post.Link)
+		item := feedItem{
+			Title:     post.Title,
+			Link:      absURL(site, link),
+			Published: post.Date,
+			Updated:   post.Updated,
+			Summary:   post.Summary,
+			HTML:      html.EscapeString(post.Summary),
+		}
+		for _, tag := range append(post.Categories, post.Tags...) {
+			item.Categories = append(item.Categories, tag.Name)
+		}
+		if FeedFull {
+			if content, err := os.ReadFile(post.file); err == nil {
+				if format, ok := formatOf(post.file); ok {
+					rendered := renderFile(post.file, format, pathKey(post.file), content, wiki)
+					item.HTML = absLinks(site, string(rendered.HTML))
+				}
+			}
+		}
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+		f.Items = append(f.Items, item)
+	}
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+	return f
+}
+
+// absLinks 将 HTML 中以 / 开头的站内链接与 srcset 中的图片地址转换为完整地址，供阅读器中打开
+func absLinks(site, html string) string {
+	html = rootLinkRegexp.ReplaceAllString(html, `$1="`+site+`/$2"`)
+	return srcsetRegexp.ReplaceAllStringFunc(html, func(m string) string {
+		sub := srcsetRegexp.FindStringSubmatch(m)
+		candidates := strings.Split(sub[2], ",")
+		for i, c := range candidates {
+			c = strings.TrimSpace(c)
+			if strings.HasPrefix(c, "/") && !strings.HasPrefix(c, "//") {
+				c = site + c
+			}
+			candidates[i] = c
+		}
+		return sub[1] + `="` + strings.Join(candidates, ", ") + `"`
+	})
+}
+
+// rssFeed RSS 2.0
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+}
+
+// atomFeed Atom 1.0
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// jsonFeed JSON Feed 1.1
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html"`
+	Summary       string   `json:"summary,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// writeXML 输出带 XML 声明的内容
+func writeXML(ctx iris.Context, contentType string, v interface{}) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		ctx.StatusCode(500)
+		return
+	}
+	ctx.ContentType(contentType)
+	ctx.Write([]byte(xml.Header))
+	ctx.Write(out)
+}
+
+// rssHandler 输出 RSS 2.0 订阅
+func rssHandler(ctx iris.Context) {
+	f := buildFeed(ctx, "/feed.xml")
+	rss := rssFeed{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Title,
+		LastBuildDate: f.Updated.Format(time.RFC1123Z),
+		AtomLink:      atomLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+	}}
+	for _, item := range f.Items {
+		rss.Channel.Items = append(rss.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        item.Link,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Description: item.HTML,
+			Categories:  item.Categories,
+		})
+	}
+	writeXML(ctx, "application/rss+xml", rss)
+}
+
+// atomHandler 输出 Atom 订阅
+func atomHandler(ctx iris.Context) {
+	f := buildFeed(ctx, "/atom.xml")
+	atom := atomFeed{
+		Title:   f.Title,
+		ID:      f.Link,
+		Updated: f.Updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.Link,
+			Link:      atomLink{Href: item.Link, Rel: "alternate", Type: "text/html"},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Body: item.HTML},
+		}
+		if FeedFull {
+			entry.Summary = item.Summary
+		}
+		for _, name := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: name})
+		}
+		atom.Entries = append(atom.Entries, entry)
+	}
+	writeXML(ctx, "application/atom+xml", atom)
+}
+
+// jsonFeedHandler 输出 JSON Feed 订阅
+func jsonFeedHandler(ctx iris.Context) {
+	f := buildFeed(ctx, "/feed.json")
+	feed := jsonFeed{Version: "https://jsonfeed.org/version/1.1", Title: f.Title, HomePageURL: f.Link, FeedURL: f.Self, Items: make([]jsonFeedItem, 0, len(f.Items))}
+	for _, item := range f.Items {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            item.Link,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.HTML,
+			Summary:       item.Summary,
+			DatePublished: item.Published.Format(time.RFC3339),
+			DateModified:  item.Updated.Format(time.RFC3339),
+			Tags:          item.Categories,
+		})
+	}
+	out, err := json.Marshal(feed)
+	if err != nil {
+		ctx.StatusCode(500)
+		return
+	}
+	ctx.ContentType("application/feed+json")
+	ctx.Write(out)
+}
+
+// feedQuery 页面对应的订阅参数，用于 layout.html 中的 <link rel="alternate">
+func feedQuery(param, value string) string {
+	return fmt.Sprintf("?%s=%s", param, url.QueryEscape(value))
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+)
+
+// ContentFormat 文章的内容格式，导航、路由、渲染与索引均按文件后缀查找对应的格式
+type ContentFormat struct {
+	Ext    string                                   // 文件后缀，如 .md
+	Title  func(content []byte, name string) string // 从内容中解析标题，name 为文件名，为空时取文件名
+	Source func(content []byte) articleSource       // 提取供索引的内容
+	Render func(c *renderContext, content []byte) *Rendered
+}
+
+// articleSource 文章中供索引的内容
+type articleSource struct {
+	FrontMatter utils.FrontMatter
+	Markdown    string // 用于解析 wiki 链接的 Markdown
+	Text        string // 供搜索的文本
+	Stats       Stats
+}
+
+// contentFormats 支持的全部内容格式，同一路径存在多个文件时按此顺序优先
+var contentFormats = []*ContentFormat{
+	{Ext: ".md", Title: utils.ContentTitle, Source: markdownSource, Render: (*renderContext).render},
+	{Ext: utils.NotebookExt, Title: utils.NotebookTitle, Source: notebookSource, Render: (*renderContext).renderNotebook},
+	{Ext: ".org", Title: orgTitle, Source: orgSource, Render: (*renderContext).renderOrg},
+	{Ext: ".txt", Source: plainTextSource, Render: (*renderContext).renderPlainText},
+	{Ext: ".html", Title: htmlTitle, Source: htmlSource, Render: (*renderContext).renderHTML},
+	{Ext: ".csv", Source: dataSource(','), Render: (*renderContext).renderData},
+	{Ext: ".tsv", Source: dataSource('\t'), Render: (*renderContext).renderData},
+}
+
+var (
+	formats    map[string]*ContentFormat // 已启用的格式：后缀 -> 格式
+	formatExts []string                  // 已启用格式的后缀，按优先顺序排列
+)
+
+func init() {
+	setFormats(nil)
+}
+
+// setFormats 按顺序启用指定后缀的格式，如 md、org，同名文件靠前的格式优先；为空时启用全部格式
+func setFormats(exts []string) {
+	registry := make(map[string]*ContentFormat)
+	for _, f := range contentFormats {
+		registry[f.Ext] = f
+	}
+	enabled := contentFormats
+	if len(exts) > 0 {
+		enabled = nil
+		for _, ext := range exts {
+			ext = "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+			if f, ok := registry[ext]; ok {
+				enabled = append(enabled, f)
+			} else {
+				log.Printf("Unknown content format %q", ext)
+			}
+		}
+	}
+	formats = make(map[string]*ContentFormat)
+	formatExts = formatExts[:0]
+	titles := make(map[string]func([]byte, string) string)
+	for _, f := range enabled {
+		if _, ok := formats[f.Ext]; ok {
+			continue
+		}
+		formats[f.Ext] = f
+		formatExts = append(formatExts, f.Ext)
+		titles[f.Ext] = f.Title
+	}
+	utils.SetFormats(titles, append([]string(nil), formatExts...))
+}
+
+// formatOf 返回文件对应的格式
+func formatOf(file string) (*ContentFormat, bool) {
+	f, ok := formats[path.Ext(file)]
+	return f, ok
+}
+
+// trimFormatExt 去掉链接目标中已启用格式的后缀，如 setup.md 转换为 setup
+func trimFormatExt(target string) string {
+	if _, ok := formatOf(target); ok {
+		return strings.TrimSuffix(target, path.Ext(target))
+	}
+	return target
+}
+
+// formatRegexp 匹配已启用格式的文件，用于监听文件变化
+func formatRegexp() *regexp.Regexp {
+	quoted := make([]string, 0, len(formatExts))
+	for _, ext := range formatExts {
+		quoted = append(quoted, regexp.QuoteMeta(ext))
+	}
+	return regexp.MustCompile(`(` + strings.Join(quoted, "|") + `)$`)
+}
+
+// articleFile 返回访问路径对应的文章文件及其格式
+func articleFile(f string) (string, *ContentFormat, bool) {
+	for _, ext := range formatExts {
+		file := MdDir + "/" + f + ext
+		if finfo, err := os.Stat(file); err == nil && !finfo.IsDir() {
+			return file, formats[ext], true
+		}
+	}
+	return "", nil, false
+}
+
+// readSource 按文件的格式提取供索引的内容，未知格式返回 false
+func readSource(file string, content []byte) (articleSource, bool) {
+	f, ok := formatOf(file)
+	if !ok {
+		return articleSource{}, false
+	}
+	return f.Source(content), true
+}
+
+// markdownSource Markdown 文章供索引的内容
+func markdownSource(content []byte) articleSource {
+	fm, body := utils.ParseFrontMatter(content)
+	return articleSource{FrontMatter: fm, Markdown: string(body), Text: string(body), Stats: textStats(body)}
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+)
+
+// 匹配 HTML 片段中的标题
+var htmlHeadingRegexp = regexp.MustCompile(`(?is)<h([1-6])([^>]*)>(.*?)</h[1-6]>`)
+
+// 匹配标签中已有的 id 属性
+var htmlIDRegexp = regexp.MustCompile(`(?i)\bid\s*=\s*"([^"]*)"`)
+
+// 匹配 HTML 片段中的链接与图片地址
+var htmlLinkRegexp = regexp.MustCompile(`(?i)(<(?:a|img)\s[^>]*?\b(?:href|src)\s*=\s*")([^"]*)(")`)
+
+// textPolicy 去掉全部标签，用于提取 HTML 片段中的文本
+var textPolicy = bluemonday.StrictPolicy()
+
+// renderPlainText 纯文本按原样显示
+func (c *renderContext) renderPlainText(content []byte) *Rendered {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	return &Rendered{
+		HTML:  template.HTML(`<pre class="plain-text">` + html.EscapeString(text) + `</pre>`),
+		Stats: plainStats(text),
+		Deps:  c.deps,
+	}
+}
+
+// plainTextSource 纯文本供索引的内容
+func plainTextSource(content []byte) articleSource {
+	return articleSource{Text: string(content), Stats: plainStats(string(content))}
+}
+
+// renderHTML 渲染 HTML 片段：支持 front matter，开头的一级标题作为文章标题，
+// 为标题生成 ID 与大纲，解析相对链接，并按文章所在目录的策略清理
+func (c *renderContext) renderHTML(content []byte) *Rendered {
+	fm, body := utils.ParseFrontMatter(content)
+	c.Title = strings.TrimSpace(fm.Title)
+	fragment := strings.TrimSpace(string(body))
+
+	title := c.Title
+	if m := htmlHeadingRegexp.FindStringSubmatchIndex(fragment); m != nil && m[0] == 0 && fragment[m[2]:m[3]] == "1" {
+		h1 := htmlText(fragment[m[6]:m[7]])
+		if title == "" || strings.EqualFold(h1, title) {
+			title = h1
+			fragment = fragment[m[1]:]
+		}
+	}
+
+	fragment = htmlHeadingRegexp.ReplaceAllStringFunc(fragment, func(m string) string {
+		sub := htmlHeadingRegexp.FindStringSubmatch(m)
+		text := htmlText(sub[3])
+		attrs := sub[2]
+		id := blackfriday.SanitizedAnchorName(text)
+		if idm := htmlIDRegexp.FindStringSubmatch(attrs); idm != nil {
+			id = idm[1]
+			attrs = htmlIDRegexp.ReplaceAllString(attrs, "")
+		}
+		id = c.uniqueID(id)
+		level := int(sub[1][0] - '0')
+		c.outline = append(c.outline, Heading{Level: level, ID: id, Title: text})
+		return fmt.Sprintf(`<h%d id="%s"%s><a class="anchor" href="#%s"><span class="octicon octicon-link"></span></a>%s</h%d>`,
+			level, html.EscapeString(id), attrs, html.EscapeString(id), sub[3], level)
+	})
+	fragment = htmlLinkRegexp.ReplaceAllStringFunc(fragment, func(m string) string {
+		sub := htmlLinkRegexp.FindStringSubmatch(m)
+		return sub[1] + html.EscapeString(resolveLink(c.Path, html.UnescapeString(sub[2]))) + sub[3]
+	})
+
+	if title == "" {
+		for _, h := range c.outline {
+			if h.Level == 1 {
+				title = h.Title
+				break
+			}
+		}
+	}
+	return &Rendered{
+		Title:       title,
+		HTML:        template.HTML(sanitize(c.Path, []byte(fragment))),
+		Outline:     c.outline,
+		Stats:       plainStats(htmlText(fragment)),
+		FrontMatter: fm,
+		Deps:        c.deps,
+	}
+}
+
+// htmlTitle HTML 片段的标题，依次取 front matter 中的 title、第一个一级标题、文件名
+func htmlTitle(content []byte, name string) string {
+	fm, body := utils.ParseFrontMatter(content)
+	if title := strings.TrimSpace(fm.Title); title != "" {
+		return title
+	}
+	for _, m := range htmlHeadingRegexp.FindAllStringSubmatch(string(body), -1) {
+		if m[1] == "1" {
+			if title := htmlText(m[3]); title != "" {
+				return title
+			}
+		}
+	}
+	return utils.NameTitle(name)
+}
+
+// htmlSource HTML 片段供索引的内容
+func htmlSource(content []byte) articleSource {
+	fm, body := utils.ParseFrontMatter(content)
+	text := htmlText(string(body))
+	return articleSource{FrontMatter: fm, Text: text, Stats: plainStats(text)}
+}
+
+// htmlText 去掉 HTML 中的标签、脚本与样式，返回纯文本
+func htmlText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(textPolicy.Sanitize(s)))
+}
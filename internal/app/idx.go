@@ -12,10 +12,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/gaowei-space/markdown-blog/internal/utils"
 	_ "github.com/glebarez/go-sqlite"
 	"github.com/radovskyb/watcher"
 	"github.com/urfave/cli/v2"
@@ -28,6 +30,7 @@ var (
 	gofoundRemove = "http://127.0.0.1:5678/api/index/remove?database=default"
 	gofoundQuery  = "http://127.0.0.1:5678/api/query?database=default"
 	gofoundDrop   = "http://127.0.0.1:5678/api/db/drop?database=default"
+	indexer       *Indexer
 )
 
 func exists(path string) bool {
@@ -49,6 +52,7 @@ func md5sum(path string) string {
 	return ""
 }
 
+// RunIndex 创建索引服务并发布到 indexer 后返回，首次遍历与文件监听在后台进行
 func RunIndex(ctx *cli.Context) {
 	log.Printf("[INDEXSERVER] RUNNING INDEX SERVER....")
 	mdDir := ctx.String("dir")
@@ -56,9 +60,12 @@ func RunIndex(ctx *cli.Context) {
 	forceidx := ctx.Bool("forceidx")
 
 	i := NewIndexer(mdDir, idxdb, forceidx, ctx.Context)
-	i.FirstRun()
-	go i.Watch()
-	i.Run()
+	indexer = i
+	go func() {
+		i.FirstRun()
+		go i.Watch()
+		i.Run()
+	}()
 }
 
 func NewIndexer(mdDir, idxdb string, force bool, ctx context.Context) *Indexer {
@@ -99,6 +106,15 @@ func (i *Indexer) InitDB(idxdb string) {
 	if _, err := i.db.Exec("CREATE TABLE IF NOT EXISTS articles (id INTEGER PRIMARY KEY AUTOINCREMENT, path TEXT, md5sum TEXT, modtime DATETIME DEFAULT CURRENT_TIMESTAMP)"); err != nil {
 		log.Fatal("[INDEXSERVER] CANNOT CREATE TABLE.")
 	}
+	if _, err := i.db.Exec("CREATE TABLE IF NOT EXISTS links (src TEXT, dst TEXT)"); err != nil {
+		log.Fatal("[INDEXSERVER] CANNOT CREATE TABLE links.")
+	}
+	i.db.Exec("CREATE INDEX IF NOT EXISTS links_dst ON links (dst)")
+	if _, err := i.db.Exec("CREATE TABLE IF NOT EXISTS tags (src TEXT, kind TEXT, name TEXT)"); err != nil {
+		log.Fatal("[INDEXSERVER] CANNOT CREATE TABLE tags.")
+	}
+	i.db.Exec("CREATE INDEX IF NOT EXISTS tags_name ON tags (kind, name COLLATE NOCASE)")
+	i.db.Exec("CREATE INDEX IF NOT EXISTS tags_src ON tags (src)")
 }
 
 func (i *Indexer) InitWatcher() {
@@ -112,8 +128,8 @@ func (i *Indexer) InitWatcher() {
 	w.FilterOps(watcher.Create, watcher.Remove, watcher.Write, watcher.Move, watcher.Rename)
 	// Only files that match the regular expression during file listings
 	// will be watched.
-	r := regexp.MustCompile(`\.md$`)
-	w.AddFilterHook(watcher.RegexFilterHook(r, false))
+	// 同时监听目录中的导航配置 _meta.yml
+	w.AddFilterHook(watcher.RegexFilterHook(regexp.MustCompile(formatRegexp().String()+"|"+regexp.QuoteMeta(utils.MetaFile)+"$"), false))
 	log.Printf("[INDEXSERVER] Watching %s", i.MdDir)
 	// Watch this folder for changes.
 	// if err := w.Add(i.MdDir); err != nil {
@@ -208,6 +224,10 @@ func (i *Indexer) FirstRun() {
 	// Print a list of all of the files and folders currently
 	// being watched and their paths.
 	for path, f := range i.w.WatchedFiles() {
+		// _meta.yml 等导航配置只用于导航，不作为文章索引
+		if _, ok := formatOf(path); !ok {
+			continue
+		}
 		count++
 		if a, ok := i.Find(path); ok && a != nil {
 			if i.Force {
@@ -229,6 +249,8 @@ func (i *Indexer) FirstRun() {
 		} else {
 			i.AddArticle(path)
 		}
+		i.IndexLinks(path)
+		i.IndexTags(path)
 	}
 	log.Printf("[INDEXSERVER] Startup Run Processed: %d files", count)
 }
@@ -242,6 +264,17 @@ func (i *Indexer) Run() {
 			return
 		case event := <-i.w.Event:
 			log.Println("[INDEXSERVER] ", event) // Print the event's info.
+			// 更新导航的目录树与 wiki 链接的解析索引
+			updateTree(event)
+			if _, ok := formatOf(event.Path); !ok {
+				continue
+			}
+			if event.Op != watcher.Write {
+				// 文章增删后清除全部渲染缓存
+				articleCache.Purge()
+			} else {
+				articleCache.Invalidate(event.Path)
+			}
 			// log.Printf("[INDEXSERVER] event:%s, path:%s", event.Op, event.Path)
 			switch {
 			case event.Op == watcher.Remove:
@@ -260,6 +293,10 @@ func (i *Indexer) Run() {
 				// log.Printf("[INDEXSERVER] Rename EVENT: %s -> %s", event.OldPath, event.Path)
 				i.MoveArticle(event.OldPath, event.Path)
 			}
+			if event.Op != watcher.Write {
+				// 页面增删后链接的解析结果可能变化，重新解析受影响文章的链接
+				i.RelinkArticles(pathKey(event.Path), pathKey(event.OldPath))
+			}
 		case err := <-i.w.Error:
 			log.Println(err)
 		case <-i.w.Closed:
@@ -273,6 +310,8 @@ func (i *Indexer) AddArticle(path string) {
 	doc := NewDocument(path)
 	if _, ok := i.Insert(doc); ok {
 		indexDoc(doc)
+		i.IndexLinks(path)
+		i.IndexTags(path)
 	}
 }
 
@@ -280,10 +319,12 @@ func (i *Indexer) DelArticle(path string) {
 	if DEBUG {
 		log.Printf("[INDEXSERVER] DEL: %s", path)
 	}
-	if doc, ok := i.Find(path); ok {
+	if doc, ok := i.Find(path); ok && doc != nil {
 		if _, ok := i.Delete(path); ok {
 			removeDoc(doc)
 		}
+		i.DeleteLinks(path)
+		i.DeleteTags(path)
 	}
 }
 
@@ -291,12 +332,14 @@ func (i *Indexer) UpdateArticle(path string) {
 	if DEBUG {
 		log.Printf("[INDEXSERVER] UPDATE: %s", path)
 	}
-	if a, ok := i.Find(path); ok {
+	if a, ok := i.Find(path); ok && a != nil {
 		b := NewDocument(path)
 		if changed := a.Compare(b); changed {
 			b.Id = a.Id
 			if _, ok := i.Update(b); ok {
 				indexDoc(b)
+				i.IndexLinks(path)
+				i.IndexTags(path)
 			}
 		}
 	} else {
@@ -312,6 +355,174 @@ func (i *Indexer) MoveArticle(oldPath, path string) {
 	i.AddArticle(path)
 }
 
+// IndexLinks 解析文章中的 wiki 链接并写入 links 表
+func (i *Indexer) IndexLinks(path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("[INDEXSERVER] read file %s err: %s", path, err)
+		return
+	}
+	wiki := getWiki()
+	from := pathKey(path)
+	i.DeleteLinks(path)
+	seen := make(map[string]bool)
+	src, _ := readSource(path, content)
+	for _, l := range parseWikiLinks(src.Markdown) {
+		if l.Target == "" {
+			continue
+		}
+		dst, _ := wiki.destination(l, from)
+		if seen[dst] || dst == from {
+			continue
+		}
+		seen[dst] = true
+		if _, err := i.db.Exec("INSERT INTO links (src,dst) VALUES (?,?)", path, dst); err != nil {
+			log.Printf("[INDEXSERVER] INSERT LINK %s -> %s ERROR: %s", path, dst, err)
+		}
+	}
+}
+
+// RelinkArticles 页面 keys 增删后重新解析受影响文章的 wiki 链接：
+// 链接目标与增删的页面同名的文章，以及链接目标不存在的文章
+func (i *Indexer) RelinkArticles(keys ...string) {
+	names := make(map[string]bool)
+	for _, key := range keys {
+		if key != "" && key != "." {
+			names[strings.ToLower(path.Base(key))] = true
+		}
+	}
+	rows, err := i.db.Query("SELECT DISTINCT src, dst FROM links")
+	if err != nil {
+		log.Printf("[INDEXSERVER] QUERY LINKS ERROR: %s", err)
+		return
+	}
+	wiki := getWiki()
+	sources := make(map[string]bool)
+	for rows.Next() {
+		var src, dst string
+		if err := rows.Scan(&src, &dst); err != nil {
+			continue
+		}
+		dst = strings.ToLower(dst)
+		if _, ok := wiki.byKey[dst]; !ok || names[path.Base(dst)] {
+			sources[src] = true
+		}
+	}
+	rows.Close()
+	for src := range sources {
+		i.IndexLinks(src)
+	}
+}
+
+func (i *Indexer) DeleteLinks(path string) {
+	if _, err := i.db.Exec("DELETE FROM links WHERE src=?", path); err != nil {
+		log.Printf("[INDEXSERVER] DELETE LINKS %s ERROR: %s", path, err)
+	}
+}
+
+// Backlinks 返回链接到 dst 的文章路径
+func (i *Indexer) Backlinks(dst string) []string {
+	var paths []string
+	rows, err := i.db.Query("SELECT DISTINCT src FROM links WHERE dst=? COLLATE NOCASE ORDER BY src", dst)
+	if err != nil {
+		log.Printf("[INDEXSERVER] QUERY BACKLINKS %s ERROR: %s", dst, err)
+		return paths
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var src string
+		if err := rows.Scan(&src); err == nil {
+			paths = append(paths, src)
+		}
+	}
+	return paths
+}
+
+// ModTimes 返回全部文章的路径与索引中记录的修改时间
+func (i *Indexer) ModTimes() map[string]time.Time {
+	times := make(map[string]time.Time)
+	rows, err := i.db.Query("SELECT path, modtime FROM articles")
+	if err != nil {
+		log.Printf("[INDEXSERVER] QUERY MODTIMES ERROR: %s", err)
+		return times
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var path string
+		var modTime time.Time
+		if err := rows.Scan(&path, &modTime); err == nil {
+			times[path] = modTime
+		}
+	}
+	return times
+}
+
+// 标签表中的类型
+const (
+	tagKind      = "tag"
+	categoryKind = "category"
+)
+
+// IndexTags 读取文章 front matter 中的标签与分类并写入 tags 表
+func (i *Indexer) IndexTags(path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("[INDEXSERVER] read file %s err: %s", path, err)
+		return
+	}
+	i.DeleteTags(path)
+	src, _ := readSource(path, content)
+	for kind, names := range map[string][]string{tagKind: src.FrontMatter.Tags, categoryKind: src.FrontMatter.Categories} {
+		for _, name := range names {
+			if _, err := i.db.Exec("INSERT INTO tags (src,kind,name) VALUES (?,?,?)", path, kind, name); err != nil {
+				log.Printf("[INDEXSERVER] INSERT TAG %s %s ERROR: %s", path, name, err)
+			}
+		}
+	}
+}
+
+func (i *Indexer) DeleteTags(path string) {
+	if _, err := i.db.Exec("DELETE FROM tags WHERE src=?", path); err != nil {
+		log.Printf("[INDEXSERVER] DELETE TAGS %s ERROR: %s", path, err)
+	}
+}
+
+// Tags 返回某类标签的全部名称及其文章路径，名称不区分大小写
+func (i *Indexer) Tags(kind string) map[string][]string {
+	tags := make(map[string][]string)
+	rows, err := i.db.Query("SELECT name, src FROM tags WHERE kind=? ORDER BY name, src", kind)
+	if err != nil {
+		log.Printf("[INDEXSERVER] QUERY TAGS %s ERROR: %s", kind, err)
+		return tags
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, src string
+		if err := rows.Scan(&name, &src); err == nil {
+			tags[name] = append(tags[name], src)
+		}
+	}
+	return tags
+}
+
+// Tagged 返回带有某个标签的文章路径
+func (i *Indexer) Tagged(kind, name string) []string {
+	var paths []string
+	rows, err := i.db.Query("SELECT DISTINCT src FROM tags WHERE kind=? AND name=? COLLATE NOCASE ORDER BY src", kind, name)
+	if err != nil {
+		log.Printf("[INDEXSERVER] QUERY TAGGED %s %s ERROR: %s", kind, name, err)
+		return paths
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var src string
+		if err := rows.Scan(&src); err == nil {
+			paths = append(paths, src)
+		}
+	}
+	return paths
+}
+
 func NewDocument(path string) *Document {
 	f, err1 := os.Open(path)
 	if err1 != nil {
@@ -347,16 +558,13 @@ type Document struct {
 
 func (doc *Document) RelativePath() string {
 	temp := strings.Replace(doc.Path, MdDir, "", 1)
-	temp = strings.TrimRight(temp, ".md")
+	temp = strings.TrimSuffix(temp, path.Ext(temp))
 	return temp
 }
 
+// Title 文章标题，依次取 front matter 中的 title、第一个一级标题、文件名
 func (doc *Document) Title() string {
-	arr := strings.Split(doc.Path, "/")
-	if len(arr) > 0 {
-		return strings.TrimRight(arr[len(arr)-1], ".md")
-	}
-	return ""
+	return utils.FileTitle(doc.Path)
 }
 
 // Return true while modtime and md5sum not equals
@@ -377,13 +585,15 @@ func indexDoc(doc *Document) bool {
 	}
 	var article SDocument
 	if content, err := os.ReadFile(doc.Path); err == nil {
+		src, _ := readSource(doc.Path, content)
 		article = SDocument{
 			Id:   doc.Id,
-			Text: string(content),
+			Text: src.Text,
 			Document: SMetadata{
-				Path:   doc.RelativePath(),
-				Title:  doc.Title(),
-				Md5sum: doc.Md5sum,
+				Path:        doc.RelativePath(),
+				Title:       doc.Title(),
+				Md5sum:      doc.Md5sum,
+				ArticleMeta: newArticleMeta(src.Stats, doc.ModTime, src.FrontMatter),
 			},
 		}
 		data, _ := json.Marshal(article)
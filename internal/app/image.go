@@ -0,0 +1,215 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/kataras/iris/v12"
+	"github.com/russross/blackfriday/v2"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ImageCacheDir 缩放后的图片缓存目录
+var ImageCacheDir = "cache/images/"
+
+// imageRegexp 可直接访问的图片格式
+var imageRegexp = regexp.MustCompile(`.*\.(jpeg|jpg|pjpg|gif|png|webp|svg)$`)
+
+// imageWidths 按需生成的图片宽度档位，?w= 参数会取不小于它的最近一档
+var imageWidths = []int{480, 800, 1200, 1600}
+
+// imageSizes 图片在页面中的显示宽度，与正文最大宽度一致
+const imageSizes = "(max-width: 800px) 100vw, 800px"
+
+// serveImage 输出图片，带 ?w= 参数时输出缩放后的图片
+func serveImage(ctx iris.Context, file string) {
+	if StaticCache > 0 {
+		ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(StaticCache.Seconds())))
+	}
+	w, err := strconv.Atoi(ctx.URLParam("w"))
+	if err != nil || w <= 0 {
+		ctx.ServeFile(file)
+		return
+	}
+	resized, err := resizeImage(file, w)
+	if err != nil {
+		ctx.Application().Logger().Errorf("Resize Image Error '%s': %s", file, err)
+		ctx.ServeFile(file)
+		return
+	}
+	ctx.ServeFile(resized)
+}
+
+// imageWidth 取不小于 w 的最近一档宽度
+func imageWidth(w int) int {
+	for _, width := range imageWidths {
+		if width >= w {
+			return width
+		}
+	}
+	return imageWidths[len(imageWidths)-1]
+}
+
+// resizeImage 生成缩放后的图片并缓存在 ImageCacheDir 中，返回缓存文件路径。
+// SVG、动图以及宽度不超过目标宽度的图片直接返回原文件
+func resizeImage(file string, w int) (string, error) {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == ".svg" {
+		return file, nil
+	}
+	finfo, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	w = imageWidth(w)
+
+	key := utils.MD5(fmt.Sprintf("%s|%d|%d", file, finfo.ModTime().UnixNano(), w))
+	for _, cacheExt := range []string{".jpg", ".png"} {
+		cached := filepath.Join(ImageCacheDir, key+cacheExt)
+		if _, err := os.Stat(cached); err == nil {
+			return cached, nil
+		}
+	}
+
+	src, err := decodeImage(file, ext)
+	if err != nil {
+		return "", err
+	}
+	if src == nil || src.Bounds().Dx() <= w {
+		return file, nil
+	}
+
+	bounds := src.Bounds()
+	h := bounds.Dy() * w / bounds.Dx()
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	// 不透明的图片输出为 JPEG，否则输出为 PNG
+	cacheExt := ".png"
+	if ext == ".jpg" || ext == ".jpeg" || ext == ".pjpg" || dst.Opaque() {
+		cacheExt = ".jpg"
+	}
+	if err := os.MkdirAll(ImageCacheDir, 0777); err != nil {
+		return "", err
+	}
+	cached := filepath.Join(ImageCacheDir, key+cacheExt)
+	tmp, err := os.CreateTemp(ImageCacheDir, key+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if cacheExt == ".jpg" {
+		err = jpeg.Encode(tmp, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(tmp, dst)
+	}
+	tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), cached)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return cached, nil
+}
+
+// decodeImage 解码图片，动图返回 nil
+func decodeImage(file, ext string) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if ext == ".gif" {
+		g, err := gif.DecodeAll(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(g.Image) != 1 {
+			return nil, nil
+		}
+		return g.Image[0], nil
+	}
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// imageConfig 读取图片的原始宽高，SVG 等无法解码的格式返回 false
+func imageConfig(file string) (image.Config, bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return image.Config{}, false
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	return config, err == nil
+}
+
+// localImage 返回站内图片对应的文件路径
+func localImage(dest string) (string, bool) {
+	if !strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "//") {
+		return "", false
+	}
+	p, err := url.PathUnescape(strings.SplitN(strings.SplitN(dest, "#", 2)[0], "?", 2)[0])
+	if err != nil || !imageRegexp.MatchString(strings.ToLower(p)) {
+		return "", false
+	}
+	file := filepath.Join(MdDir, filepath.FromSlash(p))
+	if !strings.HasPrefix(file, MdDir+string(filepath.Separator)) {
+		return "", false
+	}
+	if finfo, err := os.Stat(file); err != nil || finfo.IsDir() {
+		return "", false
+	}
+	return file, true
+}
+
+// renderImage 为站内图片输出 srcset、懒加载与原始宽高，返回 false 时按默认方式渲染
+func (r *articleRenderer) renderImage(w io.Writer, node *blackfriday.Node) bool {
+	dest := string(node.LinkData.Destination)
+	file, ok := localImage(dest)
+	if !ok || strings.Contains(dest, "?") {
+		return false
+	}
+
+	// 地址中的空格与逗号需要转义，否则 srcset 无法解析
+	src := strings.NewReplacer(" ", "%20", ",", "%2C").Replace(dest)
+	fmt.Fprintf(w, `<img src="%s" alt="%s"`, html.EscapeString(src), html.EscapeString(nodeText(node)))
+	if node.LinkData.Title != nil {
+		fmt.Fprintf(w, ` title="%s"`, html.EscapeString(string(node.LinkData.Title)))
+	}
+	if config, ok := imageConfig(file); ok && config.Width > 0 {
+		fmt.Fprintf(w, ` width="%d" height="%d"`, config.Width, config.Height)
+		if !strings.HasSuffix(strings.ToLower(file), ".gif") {
+			var srcset []string
+			for _, width := range imageWidths {
+				if width < config.Width {
+					srcset = append(srcset, fmt.Sprintf("%s?w=%d %dw", src, width, width))
+				}
+			}
+			if len(srcset) > 0 {
+				srcset = append(srcset, fmt.Sprintf("%s %dw", src, config.Width))
+				fmt.Fprintf(w, ` srcset="%s" sizes="%s"`, html.EscapeString(strings.Join(srcset, ", ")), imageSizes)
+			}
+		}
+	}
+	io.WriteString(w, ` loading="lazy" decoding="async" />`)
+	return true
+}
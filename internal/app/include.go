@@ -0,0 +1,187 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/russross/blackfriday/v2"
+)
+
+// 匹配 ![[snippets/install.md#Linux]] 形式的嵌入
+var embedRegexp = regexp.MustCompile(`!\[\[([^\[\]|#\n]+)(?:#([^\[\]|\n]*))?(?:\|[^\[\]\n]*)?\]\]`)
+
+// 匹配 {{< include "snippets/install.md#Linux" >}} 形式的嵌入
+var includeRegexp = regexp.MustCompile(`\{\{<\s*include\s+"([^"#\n]+)(?:#([^"\n]*))?"\s*>\}\}`)
+
+// 匹配 ATX 标题行
+var atxHeadingRegexp = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+
+// maxIncludeDepth 嵌入的最大层数
+const maxIncludeDepth = 10
+
+// expandIncludes 展开文章中嵌入的其他 Markdown 文件或其中的一节，
+// file 为当前文件路径，stack 为正在展开的文件，用于检测循环嵌入
+func (c *renderContext) expandIncludes(src, file string, stack []string) string {
+	stack = append(stack, filepath.Clean(file))
+	expand := func(target, section string) string {
+		target, section = strings.TrimSpace(target), strings.TrimSpace(section)
+		if imageRegexp.MatchString(strings.ToLower(target)) {
+			return fmt.Sprintf("![%s](%s)", path.Base(target), target)
+		}
+		included, err := c.resolveInclude(target, file)
+		if err != nil {
+			return includeError(target, err)
+		}
+		for _, f := range stack {
+			if f == included {
+				return includeError(target, fmt.Errorf("circular include %s", strings.Join(append(relPaths(stack), relPath(included)), " -> ")))
+			}
+		}
+		if len(stack) > maxIncludeDepth {
+			return includeError(target, fmt.Errorf("include depth exceeds %d", maxIncludeDepth))
+		}
+
+		c.addDep(included)
+		content, err := os.ReadFile(included)
+		if err != nil {
+			return includeError(target, err)
+		}
+		_, content = utils.ParseFrontMatter(content)
+		text := strings.ReplaceAll(string(content), "\r\n", "\n")
+		if section != "" {
+			var ok bool
+			if text, ok = extractSection(text, section); !ok {
+				return includeError(target+"#"+section, fmt.Errorf("section not found"))
+			}
+		}
+		text = rebaseLinks(c.expandIncludes(text, included, stack), relPath(included))
+		return "\n" + strings.TrimRight(text, "\n") + "\n"
+	}
+
+	return transformMarkdown(src, func(text string) string {
+		text = includeRegexp.ReplaceAllStringFunc(text, func(m string) string {
+			sub := includeRegexp.FindStringSubmatch(m)
+			return expand(sub[1], sub[2])
+		})
+		return embedRegexp.ReplaceAllStringFunc(text, func(m string) string {
+			sub := embedRegexp.FindStringSubmatch(m)
+			return expand(sub[1], sub[2])
+		})
+	})
+}
+
+// resolveInclude 解析嵌入的文件路径：先相对当前文件所在目录，再相对 MdDir，
+// 最后按 wiki 链接的规则相对当前文件查找，结果必须位于 MdDir 内
+func (c *renderContext) resolveInclude(target, from string) (string, error) {
+	name := filepath.FromSlash(target)
+	if path.Ext(target) == "" {
+		name += ".md"
+	}
+	var candidates []string
+	if !strings.HasPrefix(target, "/") {
+		candidates = append(candidates, filepath.Join(filepath.Dir(from), name))
+	}
+	candidates = append(candidates, filepath.Join(MdDir, name))
+	if c.Wiki != nil {
+		if node := c.Wiki.resolve(target, pathKey(from)); node != nil {
+			candidates = append(candidates, node.Path)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if !inDir(MdDir, candidate) {
+			continue
+		}
+		if finfo, err := os.Stat(candidate); err == nil && !finfo.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("file not found")
+}
+
+// inDir 判断 file 是否位于 dir 内
+func inDir(dir, file string) bool {
+	rel, err := filepath.Rel(dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// extractSection 提取标题为 section 的一节，直到下一个同级或更高级的标题
+func extractSection(text, section string) (string, bool) {
+	anchor := blackfriday.SanitizedAnchorName(section)
+	var out []string
+	level := 0
+	fence := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		} else if f := fenceMarker(trimmed); f != "" {
+			fence = f
+		} else if m := atxHeadingRegexp.FindStringSubmatch(trimmed); m != nil {
+			switch {
+			case level > 0 && len(m[1]) <= level:
+				return strings.Join(out, ""), true
+			case level == 0 && (strings.EqualFold(m[2], section) || blackfriday.SanitizedAnchorName(m[2]) == anchor):
+				level = len(m[1])
+			}
+		}
+		if level > 0 {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, ""), level > 0
+}
+
+// includeError 嵌入失败时在文中显示的提示
+func includeError(target string, err error) string {
+	return fmt.Sprintf("\n\n> [!CAUTION] Include failed\n> `%s`: %s\n\n", strings.ReplaceAll(target, "`", ""), err)
+}
+
+// relPath 文件相对 MdDir 的路径
+func relPath(file string) string {
+	if rel, err := filepath.Rel(MdDir, file); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+func relPaths(files []string) []string {
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, relPath(f))
+	}
+	return paths
+}
+
+// addDep 记录渲染结果依赖的文件及其修改时间，文件变化后缓存失效
+func (c *renderContext) addDep(file string) {
+	if finfo, err := os.Stat(file); err == nil {
+		c.deps[file] = finfo.ModTime().UnixNano()
+	} else {
+		c.deps[file] = 0
+	}
+}
+
+// depsChanged 判断依赖的文件是否有变化
+func depsChanged(deps map[string]int64) bool {
+	for file, modtime := range deps {
+		finfo, err := os.Stat(file)
+		if err != nil {
+			if modtime != 0 {
+				return true
+			}
+			continue
+		}
+		if finfo.ModTime().UnixNano() != modtime {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+)
+
+// resolveLink 将相对于文章所在目录的链接转换为站点路由，
+// 如在 ops/deploy 中 ../dev/setup.md#linux 转换为 /dev/setup#linux，其他格式的文章同理，
+// 绝对路径、外部链接与页内锚点保持不变
+func resolveLink(from, dest string) string {
+	if dest == "" || strings.HasPrefix(dest, "#") || strings.HasPrefix(dest, "/") {
+		return dest
+	}
+	if u, err := url.Parse(dest); err != nil || u.Scheme != "" || u.Host != "" {
+		return dest
+	}
+
+	target, fragment, _ := strings.Cut(dest, "#")
+	target, query, _ := strings.Cut(target, "?")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	joined := path.Join(path.Dir(from), target)
+	if joined == ".." || strings.HasPrefix(joined, "../") {
+		// 超出 MdDir 的链接保持不变
+		return dest
+	}
+	joined = trimFormatExt(joined)
+	if joined == "." {
+		joined = ""
+	}
+
+	link := "/" + utils.CustomURLEncode(joined)
+	if query != "" {
+		link += "?" + query
+	}
+	if fragment != "" {
+		link += "#" + fragment
+	}
+	return link
+}
+
+// 匹配行内链接与图片的目标，如 [text](dest "title") 中的 dest
+var inlineLinkRegexp = regexp.MustCompile(`(\]\(\s*)(<[^<>\n]*>|[^\s()<>]+)`)
+
+// 匹配链接引用定义，如 [id]: dest，脚注定义除外
+var linkRefRegexp = regexp.MustCompile(`(?m)^( {0,3}\[[^\^\]\n][^\]\n]*\]:[ \t]*)(<[^<>\n]*>|\S+)`)
+
+// rebaseLinks 将嵌入文本中相对于被嵌入文件 from 的链接与图片转换为站点路由，
+// 使其在嵌入到其他目录的文章后仍指向原来的位置，代码块与行内代码保持不变
+func rebaseLinks(src, from string) string {
+	rebase := func(re *regexp.Regexp) func(string) string {
+		return func(m string) string {
+			sub := re.FindStringSubmatch(m)
+			dest := strings.TrimSuffix(strings.TrimPrefix(sub[2], "<"), ">")
+			if link := resolveLink(from, dest); link != dest {
+				return sub[1] + link
+			}
+			return m
+		}
+	}
+	return transformMarkdown(src, func(text string) string {
+		text = inlineLinkRegexp.ReplaceAllStringFunc(text, rebase(inlineLinkRegexp))
+		return linkRefRegexp.ReplaceAllStringFunc(text, rebase(linkRefRegexp))
+	})
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+)
+
+// transformMarkdown 对 Markdown 源文本中非代码部分应用 fn，
+// 围栏代码块（``` 或 ~~~）、缩进代码块与行内代码保持原样
+func transformMarkdown(src string, fn func(string) string) string {
+	return transformBlocks(src, func(text string) string {
+		return transformCodeSpans(text, fn)
+	})
+}
+
+// 匹配列表项的起始行
+var listItemRegexp = regexp.MustCompile(`^ {0,3}(?:[-*+]|\d{1,9}[.)])(?:[ \t]|$)`)
+
+// transformBlocks 对代码块之外的文本应用 fn，fn 每次收到的是若干完整的行。
+// 缩进代码块需以空行开始，列表中缩进的内容属于列表项，不视为代码块
+func transformBlocks(src string, fn func(string) string) string {
+	var out strings.Builder
+	var chunk []string
+	var fence string
+	code, list, blank := false, false, true
+
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		out.WriteString(fn(strings.Join(chunk, "")))
+		chunk = chunk[:0]
+	}
+
+	for _, line := range strings.SplitAfter(src, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence != "" {
+			out.WriteString(line)
+			if strings.HasPrefix(strings.TrimSpace(trimmed), fence) && strings.Trim(strings.TrimSpace(trimmed), fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		empty := strings.TrimSpace(line) == ""
+		indented := strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+		if code && (indented || empty) {
+			out.WriteString(line)
+			continue
+		}
+		code = false
+		if indented && !empty && blank && !list {
+			flush()
+			code = true
+			out.WriteString(line)
+			continue
+		}
+		if f := fenceMarker(trimmed); f != "" && len(line)-len(trimmed) < 4 {
+			flush()
+			fence = f
+			out.WriteString(line)
+			blank = false
+			continue
+		}
+		if !empty && !indented {
+			list = listItemRegexp.MatchString(line) || (list && !blank)
+		}
+		blank = empty
+		chunk = append(chunk, line)
+	}
+	flush()
+
+	return out.String()
+}
+
+// fenceMarker 返回代码块的起始标记，如 ``` 或 ~~~~，非代码块返回空
+func fenceMarker(line string) string {
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == c {
+			n++
+		}
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// transformCodeSpans 跳过行内代码，对其余文本应用 fn
+func transformCodeSpans(text string, fn func(string) string) string {
+	var out strings.Builder
+	start := 0
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := 0
+		for i+n < len(text) && text[i+n] == '`' {
+			n++
+		}
+		tick := text[i : i+n]
+		end := -1
+		for j := i + n; j < len(text); {
+			k := strings.Index(text[j:], tick)
+			if k < 0 {
+				break
+			}
+			k += j
+			m := 0
+			for k+m < len(text) && text[k+m] == '`' {
+				m++
+			}
+			if m == n {
+				end = k + n
+				break
+			}
+			j = k + m
+		}
+		if end < 0 {
+			i += n
+			continue
+		}
+		out.WriteString(fn(text[start:i]))
+		out.WriteString(text[i:end])
+		start, i = end, end
+	}
+	out.WriteString(fn(text[start:]))
+
+	return out.String()
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformMarkdown(t *testing.T) {
+	upper := func(s string) string { return strings.ToUpper(s) }
+	tests := []struct {
+		name, src, want string
+	}{
+		{"text", "a b\n", "A B\n"},
+		{"code span", "a `b` c\n", "A `b` C\n"},
+		{"double backticks", "a ``b ` c`` d\n", "A ``b ` c`` D\n"},
+		{"unclosed backtick", "a `b\n", "A `B\n"},
+		{"fence", "a\n```go\nb\n```\nc\n", "A\n```go\nb\n```\nC\n"},
+		{"tilde fence", "~~~~\nb\n~~~\nc\n~~~~\nd\n", "~~~~\nb\n~~~\nc\n~~~~\nD\n"},
+		{"indented fence is code", "a\n\n    ```\n    b\n\nc\n", "A\n\n    ```\n    b\n\nC\n"},
+		{"indented code", "a\n\n    b\n\tc\n\nd\n", "A\n\n    b\n\tc\n\nD\n"},
+		{"paragraph continuation", "a\n    b\n", "A\n    B\n"},
+		{"list continuation", "- a\n\n    b\n", "- A\n\n    B\n"},
+		{"ordered list continuation", "1. a\n\n    b\n", "1. A\n\n    B\n"},
+		{"code after list", "- a\n\nb\n\n    c\n", "- A\n\nB\n\n    c\n"},
+	}
+	for _, tt := range tests {
+		if got := transformMarkdown(tt.src, upper); got != tt.want {
+			t.Errorf("%s: transformMarkdown(%q) = %q, want %q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
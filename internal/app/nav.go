@@ -0,0 +1,260 @@
+package app
+
+import (
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/kataras/iris/v12"
+)
+
+var (
+	NavSort     = utils.SortByName // 导航中文件的排序方式
+	NavPageSize = 150              // 目录在导航中每页显示的条目数，0 为不分页
+	indexAuto   bool               // 未设置首页时以导航中的第一篇文章作为首页
+)
+
+// setNavSort 设置导航的排序方式，不支持的方式按文件名排序
+func setNavSort(mode string) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if !utils.IsSortMode(mode) {
+		log.Printf("Unknown nav sort %q, sorting by name", mode)
+		mode = utils.SortByName
+	}
+	NavSort = mode
+}
+
+// navView 生成当前请求的导航：复制要显示的节点并标记当前文章 active，不修改共享的目录树。
+// 目录的条目超过 NavPageSize 时只显示到当前文章所在的页，其余条目的数量记录在 More 中，
+// 由“显示更多”按页加载
+func navView(node *utils.Node, active string) *utils.Node {
+	view := *node
+	if (node.IsPage() || node.IsDir) && nodeKey(node) == active {
+		view.Active = "active"
+	}
+	if len(node.Children) == 0 {
+		return &view
+	}
+
+	children := visibleNodes(node.Children)
+	if NavPageSize > 0 && len(children) > NavPageSize {
+		last := -1
+		for i, child := range children {
+			if containsActive(child, active) {
+				last = i
+				break
+			}
+		}
+		if keep := (last/NavPageSize + 1) * NavPageSize; keep < len(children) {
+			view.More = len(children) - keep
+			children = children[:keep]
+		}
+	}
+	for i, child := range children {
+		children[i] = navView(child, active)
+	}
+	view.Children = children
+	return &view
+}
+
+// containsActive 判断节点是否为当前文章或包含当前文章的目录
+func containsActive(node *utils.Node, active string) bool {
+	key := nodeKey(node)
+	if node.IsDir {
+		return key == active || strings.HasPrefix(active, key+"/")
+	}
+	return node.IsPage() && key == active
+}
+
+// visibleNodes 去掉导航中隐藏的条目
+func visibleNodes(nodes []*utils.Node) []*utils.Node {
+	visible := make([]*utils.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.Hidden {
+			visible = append(visible, node)
+		}
+	}
+	return visible
+}
+
+// findDir 返回访问路径对应的目录节点，f 为空时返回根目录
+func findDir(tree utils.Node, f string) (*utils.Node, bool) {
+	if len(tree.Children) == 0 {
+		return nil, false
+	}
+	node := tree.Children[0]
+	for _, name := range strings.Split(strings.Trim(f, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		var next *utils.Node
+		for _, child := range node.Children {
+			if child.IsDir && child.Name == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, false
+		}
+		node = next
+	}
+	return node, true
+}
+
+// isDirPath 判断访问路径是否为目录且没有同名的文章
+func isDirPath(f string) bool {
+	if _, _, ok := articleFile(f); ok {
+		return false
+	}
+	finfo, err := os.Stat(MdDir + "/" + f)
+	return err == nil && finfo.IsDir()
+}
+
+// dirIndexFile 返回目录页面的文件与格式，目录中没有 index 或 README 时返回 false
+func dirIndexFile(f string) (string, *ContentFormat, bool) {
+	if !isDirPath(f) {
+		return "", nil, false
+	}
+	file := utils.DirIndex(path.Join(MdDir, f))
+	if file == "" {
+		return "", nil, false
+	}
+	format, ok := formatOf(file)
+	return file, format, ok
+}
+
+// NavPage 导航中目录的一页条目
+type NavPage struct {
+	Items []*utils.Node `json:"items"`
+	More  int           `json:"more"` // 之后剩余的条目数量
+}
+
+// navJSONHandler 按 offset 输出目录在导航中的下一页条目，用于“显示更多”
+func navJSONHandler(ctx iris.Context) {
+	dir, ok := findDir(getTree(), ctx.Params().Get("f"))
+	if !ok {
+		ctx.StatusCode(404)
+		return
+	}
+	children := visibleNodes(dir.Children)
+	offset := ctx.URLParamIntDefault("offset", 0)
+	if offset < 0 || offset > len(children) {
+		offset = len(children)
+	}
+	end := len(children)
+	if NavPageSize > 0 && offset+NavPageSize < end {
+		end = offset + NavPageSize
+	}
+	page := NavPage{Items: make([]*utils.Node, 0, end-offset), More: len(children) - end}
+	for _, child := range children[offset:end] {
+		item := *child
+		item.Children = nil
+		page.Items = append(page.Items, &item)
+	}
+	ctx.JSON(page)
+}
+
+// dirHandler 没有 index 或 README 的目录页面，列出目录中的全部条目及文章摘要
+func dirHandler(ctx iris.Context, f string) {
+	dir, ok := findDir(getTree(), f)
+	if !ok {
+		ctx.StatusCode(404)
+		return
+	}
+	entries := make([]DirEntry, 0, len(dir.Children))
+	for _, child := range visibleNodes(dir.Children) {
+		entry := DirEntry{Node: child}
+		if child.IsPage() {
+			entry.Summary = articleSummary(child.Path)
+		} else if child.Index {
+			entry.Summary = articleSummary(utils.DirIndex(child.Path))
+		}
+		entries = append(entries, entry)
+	}
+	ctx.ViewData("Title", Title)
+	ctx.ViewData("ArticleTitle", dir.ShowName)
+	ctx.ViewData("Breadcrumbs", articleNav(f).Breadcrumbs)
+	ctx.ViewData("Entries", entries)
+	ctx.ViewData("FeedQuery", feedQuery("path", f+"/"))
+	ctx.View("dir.html")
+}
+
+// DirEntry 目录页面中的条目与摘要
+type DirEntry struct {
+	*utils.Node
+	Summary string
+}
+
+// NavLink 导航中的一个链接
+type NavLink struct {
+	Title string `json:"title"`
+	Link  string `json:"link"`
+}
+
+// ArticleNav 文章在导航中的位置：面包屑与上一篇、下一篇
+type ArticleNav struct {
+	Breadcrumbs []NavLink `json:"breadcrumbs"` // 首页、所在的各级目录与文章本身
+	Prev        *NavLink  `json:"prev"`
+	Next        *NavLink  `json:"next"`
+}
+
+// articleNav 按导航中的顺序计算文章或目录的面包屑与上一篇、下一篇，
+// 上一篇与下一篇只在导航中显示的文章与有页面的目录之间查找，隐藏的文章没有上一篇与下一篇
+func articleNav(f string) ArticleNav {
+	nav := ArticleNav{Breadcrumbs: []NavLink{{Title: Title, Link: "/"}}}
+	tree := getTree()
+	if len(tree.Children) == 0 {
+		return nav
+	}
+
+	var (
+		pages   []*utils.Node
+		trail   []*utils.Node
+		current = -1
+	)
+	// visit 记录导航中的文章与有页面的目录，找到当前页面时记录面包屑
+	visit := func(node *utils.Node, parents []*utils.Node, hidden bool) {
+		if trail == nil && nodeKey(node) == f {
+			trail = parents
+			if !hidden {
+				current = len(pages)
+			}
+		}
+		if !hidden {
+			pages = append(pages, node)
+		}
+	}
+	var walk func(node *utils.Node, parents []*utils.Node, hidden bool)
+	walk = func(node *utils.Node, parents []*utils.Node, hidden bool) {
+		for _, child := range node.Children {
+			hidden := hidden || child.Hidden
+			chain := append(parents[:len(parents):len(parents)], child)
+			switch {
+			case child.IsDir:
+				if child.Index {
+					visit(child, chain, hidden)
+				} else if nodeKey(child) == f {
+					visit(child, chain, true)
+				}
+				walk(child, chain, hidden)
+			case child.IsPage():
+				visit(child, chain, hidden)
+			}
+		}
+	}
+	walk(tree.Children[0], nil, false)
+
+	for _, node := range trail {
+		nav.Breadcrumbs = append(nav.Breadcrumbs, NavLink{Title: node.ShowName, Link: node.Link})
+	}
+	if current > 0 {
+		nav.Prev = &NavLink{Title: pages[current-1].ShowName, Link: pages[current-1].Link}
+	}
+	if current >= 0 && current+1 < len(pages) {
+		nav.Next = &NavLink{Title: pages[current+1].ShowName, Link: pages[current+1].Link}
+	}
+	return nav
+}
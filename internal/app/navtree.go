@@ -0,0 +1,97 @@
+package app
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/radovskyb/watcher"
+)
+
+// navTree 内存中的目录树与 wiki 链接解析索引，首次使用时生成，由索引服务的文件变化事件增量更新。
+// 更新时生成新的树整体替换，已读取的树不会被修改，请求中可以直接使用
+var navTree struct {
+	sync.RWMutex
+	tree *utils.Node
+	wiki *wikiIndex
+}
+
+// getTree 返回当前的目录树，调用方不能修改其中的节点
+func getTree() utils.Node {
+	navTree.RLock()
+	tree := navTree.tree
+	navTree.RUnlock()
+	if tree != nil {
+		return *tree
+	}
+
+	navTree.Lock()
+	defer navTree.Unlock()
+	if navTree.tree == nil {
+		setTree(exploreTree())
+	}
+	return *navTree.tree
+}
+
+// getWiki 返回与当前目录树对应的 wiki 链接解析索引
+func getWiki() *wikiIndex {
+	getTree()
+	navTree.RLock()
+	defer navTree.RUnlock()
+	return navTree.wiki
+}
+
+// setTree 替换目录树并重新生成 wiki 链接的解析索引，调用方需持有写锁
+func setTree(tree utils.Node) {
+	navTree.tree = &tree
+	navTree.wiki = newWikiIndex(tree)
+}
+
+// exploreTree 遍历 MdDir 生成目录树
+func exploreTree() utils.Node {
+	tree, _ := utils.Explorer(treeOption())
+	return tree
+}
+
+func treeOption() utils.Option {
+	var option utils.Option
+	option.RootPath = []string{MdDir}
+	option.SubFlag = true
+	option.IgnorePath = IgnorePath
+	option.IgnoreFile = IgnoreFile
+	option.Sort = NavSort
+	return option
+}
+
+// updateTree 按文件变化事件更新目录树：SUMMARY.md 变化时重新遍历全部目录，
+// 其他文件只重新遍历其所在的目录，移动与改名时还包括原来所在的目录。
+// 目录页面决定目录的标题，变化时重新遍历上一级目录
+func updateTree(event watcher.Event) {
+	navTree.Lock()
+	defer navTree.Unlock()
+	if navTree.tree == nil {
+		return
+	}
+
+	var dirs []string
+	for _, file := range []string{event.Path, event.OldPath} {
+		if file == "" {
+			continue
+		}
+		file, _ = filepath.Abs(file)
+		if file == filepath.Join(MdDir, utils.SummaryFile) {
+			setTree(exploreTree())
+			return
+		}
+		dir := filepath.Dir(file)
+		if utils.IsDirIndex(filepath.Base(file)) && dir != MdDir {
+			dir = filepath.Dir(dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	tree := *navTree.tree
+	for _, dir := range dirs {
+		tree = utils.Rebuild(tree, dir, treeOption())
+	}
+	setTree(tree)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// notebookMimeTypes 输出有多种格式时优先选用的格式
+var notebookMimeTypes = []string{"text/html", "image/svg+xml", "image/png", "image/jpeg", "image/gif", "text/markdown", "text/plain"}
+
+// 匹配 markdown 单元格中引用附件的 attachment:name
+var attachmentRegexp = regexp.MustCompile(`\(attachment:([^)\s]+)`)
+
+// renderNotebook 渲染 Jupyter Notebook。markdown 单元格与代码单元格转换为 Markdown 后
+// 按文章的方式渲染，输出预先渲染为 HTML 并按文章的清理策略清理，再以占位符插入
+func (c *renderContext) renderNotebook(content []byte) *Rendered {
+	nb, err := utils.ParseNotebook(content)
+	if err != nil {
+		return c.render([]byte(fmt.Sprintf("> [!CAUTION] Invalid notebook\n> `%s`\n", err)))
+	}
+	rendered := c.render([]byte(c.notebookMarkdown(nb)))
+	rendered.Stats = textStats([]byte(nb.Markdown()))
+	return rendered
+}
+
+// notebookMarkdown 将 Notebook 转换为 Markdown，元数据中的标题转换为 front matter
+func (c *renderContext) notebookMarkdown(nb *utils.Notebook) string {
+	var buf strings.Builder
+	if title := strings.TrimSpace(nb.Metadata.Title); title != "" {
+		fm, _ := yaml.Marshal(map[string]string{"title": title})
+		buf.WriteString("---\n" + string(fm) + "---\n\n")
+	}
+	lang := nb.Language()
+	for _, cell := range nb.Cells {
+		source := strings.TrimRight(string(cell.Source), "\n")
+		switch cell.CellType {
+		case "markdown":
+			buf.WriteString(notebookAttachments(source, cell.Attachments) + "\n\n")
+		case "code":
+			if strings.TrimSpace(source) != "" {
+				buf.WriteString(codeFence(source, lang) + "\n\n")
+			}
+			for _, o := range cell.Outputs {
+				if output := c.notebookOutput(o); output != "" {
+					buf.WriteString("\n\n" + c.fragmentPlaceholder(string(sanitize(c.Path, []byte(output)))) + "\n\n")
+				}
+			}
+		}
+	}
+	return buf.String()
+}
+
+// notebookAttachments 将 markdown 单元格中的附件替换为 data URI
+func notebookAttachments(source string, attachments map[string]map[string]utils.NotebookText) string {
+	if len(attachments) == 0 {
+		return source
+	}
+	return attachmentRegexp.ReplaceAllStringFunc(source, func(m string) string {
+		name := attachmentRegexp.FindStringSubmatch(m)[1]
+		for mime, data := range attachments[name] {
+			if strings.HasPrefix(mime, "image/") {
+				return "(data:" + mime + ";base64," + strings.Join(strings.Fields(string(data)), "")
+			}
+		}
+		return m
+	})
+}
+
+// notebookOutput 将代码单元格的一项输出渲染为 HTML，由调用方清理
+func (c *renderContext) notebookOutput(o utils.NotebookOutput) string {
+	switch o.OutputType {
+	case "stream":
+		class := "nb-output nb-stream"
+		if o.Name == "stderr" {
+			class += " nb-stderr"
+		}
+		return notebookPre(class, string(o.Text))
+	case "error":
+		text := strings.Join(o.Traceback, "\n")
+		if text == "" {
+			text = o.EName + ": " + o.EValue
+		}
+		return notebookPre("nb-output nb-error", text)
+	}
+
+	for _, mime := range notebookMimeTypes {
+		data, ok := o.Data[mime]
+		if !ok {
+			continue
+		}
+		switch mime {
+		case "text/html":
+			return `<div class="nb-output nb-html">` + string(data) + `</div>`
+		case "image/svg+xml":
+			return notebookImage(mime, base64.StdEncoding.EncodeToString([]byte(data)))
+		case "image/png", "image/jpeg", "image/gif":
+			return notebookImage(mime, strings.Join(strings.Fields(string(data)), ""))
+		case "text/markdown":
+			return `<div class="nb-output nb-markdown">` + c.renderFragment(string(data)) + `</div>`
+		default:
+			return notebookPre("nb-output", string(data))
+		}
+	}
+	return ""
+}
+
+// notebookPre 文本形式的输出，去掉终端控制序列
+func notebookPre(class, text string) string {
+	text = strings.TrimRight(utils.StripANSI(text), "\n")
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<pre class="%s">%s</pre>`, class, html.EscapeString(text))
+}
+
+// notebookImage 图片形式的输出
+func notebookImage(mime, data string) string {
+	return fmt.Sprintf(`<div class="nb-output nb-image"><img src="data:%s;base64,%s" alt="output" /></div>`, mime, data)
+}
+
+// notebookSource Notebook 供索引的内容，正文为 markdown 单元格，搜索文本还包括代码与文本输出
+func notebookSource(content []byte) articleSource {
+	nb, err := utils.ParseNotebook(content)
+	if err != nil {
+		return articleSource{}
+	}
+	markdown := nb.Markdown()
+	return articleSource{
+		FrontMatter: utils.FrontMatter{Title: nb.Metadata.Title},
+		Markdown:    markdown,
+		Text:        nb.Text(),
+		Stats:       textStats([]byte(markdown)),
+	}
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaowei-space/markdown-blog/internal/types"
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+)
+
+// 输出按文章的清理策略清理，保留 nb-* 类名
+func TestNotebookOutputSanitized(t *testing.T) {
+	withSanitizer(t, types.Sanitizer{Preset: PresetUGC, Overrides: []string{"strict:strict"}})
+	nb := `{"nbformat": 4, "nbformat_minor": 5, "metadata": {}, "cells": [{"cell_type": "code", "source": "x", "outputs": [
+		{"output_type": "display_data", "data": {"text/html": "<button class=\"x\" onclick=\"alert(1)\">b</button><video src=\"v.mp4\"></video><span style=\"color:red\">s</span><script>alert(1)</script>"}},
+		{"output_type": "display_data", "data": {"text/markdown": "**md**"}},
+		{"output_type": "stream", "name": "stderr", "text": "warn"},
+		{"output_type": "error", "ename": "E", "evalue": "<v>", "traceback": []}
+	]}]}`
+	tests := []struct {
+		path    string
+		want    []string
+		notWant []string
+	}{
+		{"guide/nb", []string{
+			`<div class="nb-output nb-html">`, `<span style="color:red">s</span>`,
+			`<div class="nb-output nb-markdown"><p><strong>md</strong></p>`,
+			`<pre class="nb-output nb-stream nb-stderr">warn</pre>`,
+			`<pre class="nb-output nb-error">E: &lt;v&gt;</pre>`,
+		}, []string{"<button", "<video", "onclick", "<script>", `class="x"`}},
+		{"strict/nb", []string{`<div class="nb-output nb-html">`, `<pre class="nb-output nb-error">`}, []string{"<button", "<video", "style=", "<script>"}},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "nb.ipynb")
+		c := newRenderContext(file, tt.path, newWikiIndex(utils.Node{}))
+		got := string(c.renderNotebook([]byte(nb)).HTML)
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: notebook = %q, want it to contain %q", tt.path, got, w)
+			}
+		}
+		for _, w := range tt.notWant {
+			if strings.Contains(got, w) {
+				t.Errorf("%s: notebook = %q, want it without %q", tt.path, got, w)
+			}
+		}
+	}
+}
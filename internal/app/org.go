@@ -0,0 +1,219 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/russross/blackfriday/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// 匹配 #+TITLE: 标题 形式的关键字
+	orgKeywordRegexp = regexp.MustCompile(`^#\+(\w+):\s*(.*?)\s*$`)
+	// 匹配 * TODO [#A] 标题 :tag: 形式的标题
+	orgHeadingRegexp = regexp.MustCompile(`^(\*+)\s+(?:(?:TODO|DONE)\s+)?(?:\[#[A-Z]\]\s+)?(.*?)(?:\s+:[\w@#%:]+:)?\s*$`)
+	// 匹配 #+BEGIN_SRC go 等块的起止
+	orgBeginRegexp = regexp.MustCompile(`(?i)^\s*#\+begin_(\w+)\s*(.*?)\s*$`)
+	orgEndRegexp   = regexp.MustCompile(`(?i)^\s*#\+end_(\w+)\s*$`)
+	// 匹配 [[target][description]] 与 [[target]] 形式的链接
+	orgLinkRegexp = regexp.MustCompile(`\[\[([^\[\]]+)\](?:\[([^\[\]]+)\])?\]`)
+	// 匹配表格的分隔行，如 |---+---|
+	orgTableRuleRegexp = regexp.MustCompile(`^\s*\|[-+]+\|?\s*$`)
+	// 匹配 1) 形式的有序列表
+	orgOrderedRegexp = regexp.MustCompile(`^(\s*)(\d+)\)\s`)
+	// 匹配 :PROPERTIES: 等抽屉以及 SCHEDULED: 等计划行
+	orgDrawerRegexp   = regexp.MustCompile(`^\s*:[A-Z_]+:\s*$`)
+	orgPlanningRegexp = regexp.MustCompile(`^\s*(SCHEDULED|DEADLINE|CLOSED):`)
+)
+
+// orgEmphasis Org-mode 行内标记与 Markdown 的对应关系，按顺序转换
+var orgEmphasis = []struct {
+	re      *regexp.Regexp
+	replace string
+}{
+	{orgEmphasisRegexp("="), "$1`$2`$3"},
+	{orgEmphasisRegexp("~"), "$1`$2`$3"},
+	{orgEmphasisRegexp("*"), "$1**$2**$3"},
+	{orgEmphasisRegexp("/"), "${1}_${2}_$3"},
+	{orgEmphasisRegexp("+"), "$1~~$2~~$3"},
+}
+
+// orgEmphasisRegexp 匹配以 marker 包裹的行内标记，标记前为行首、空白或左括号
+func orgEmphasisRegexp(marker string) *regexp.Regexp {
+	m := regexp.QuoteMeta(marker)
+	return regexp.MustCompile(`(^|[\s(])` + m + `([^\s` + m + `](?:[^` + m + `\n]*[^\s` + m + `])?)` + m + `($|[\s.,;:!?)'"-])`)
+}
+
+// renderOrg 将 Org-mode 文档转换为 Markdown 后渲染
+func (c *renderContext) renderOrg(content []byte) *Rendered {
+	return c.render(orgToMarkdown(content))
+}
+
+// orgTitle Org-mode 文档的标题，依次取 #+TITLE、第一个一级标题、文件名
+func orgTitle(content []byte, name string) string {
+	return utils.ContentTitle(orgToMarkdown(content), name)
+}
+
+// orgSource Org-mode 文档供索引的内容
+func orgSource(content []byte) articleSource {
+	return markdownSource(orgToMarkdown(content))
+}
+
+// orgToMarkdown 将 Org-mode 文档转换为 Markdown，#+TITLE 转换为 front matter，
+// 支持标题、代码块、引用块、提示块、列表、表格、链接与行内标记
+func orgToMarkdown(content []byte) []byte {
+	var out []string
+	var title string
+	block, fence := "", ""
+	drawer, skip := false, false
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		if block != "" {
+			if m := orgEndRegexp.FindStringSubmatch(line); m != nil && strings.EqualFold(m[1], block) {
+				if fence != "" {
+					out = append(out, fence)
+				} else if isOrgCallout(block) {
+					out = append(out, ":::")
+				}
+				block, fence, skip = "", "", false
+				continue
+			}
+			switch {
+			case skip:
+			case fence != "":
+				out = append(out, line)
+			case strings.EqualFold(block, "quote"):
+				out = append(out, "> "+orgInline(line))
+			case strings.EqualFold(block, "export"):
+				out = append(out, line)
+			case strings.EqualFold(block, "comment"):
+			default:
+				out = append(out, orgInline(line))
+			}
+			continue
+		}
+
+		if drawer {
+			drawer = !strings.EqualFold(strings.TrimSpace(line), ":END:")
+			continue
+		}
+		if orgDrawerRegexp.MatchString(line) {
+			drawer = true
+			continue
+		}
+		if orgPlanningRegexp.MatchString(line) {
+			continue
+		}
+
+		if m := orgBeginRegexp.FindStringSubmatch(line); m != nil {
+			block = m[1]
+			switch strings.ToLower(block) {
+			case "src":
+				fence = "```"
+				lang := ""
+				if args := strings.Fields(m[2]); len(args) > 0 {
+					lang = args[0]
+				}
+				out = append(out, fence+lang)
+			case "example":
+				fence = "```"
+				out = append(out, fence)
+			case "export":
+				// 只保留 HTML 导出块，其他格式的导出块跳过
+				skip = !strings.EqualFold(strings.TrimSpace(m[2]), "html")
+			default:
+				if isOrgCallout(block) {
+					out = append(out, ":::"+strings.ToLower(block)+" "+m[2])
+				}
+			}
+			continue
+		}
+		if m := orgKeywordRegexp.FindStringSubmatch(line); m != nil {
+			if strings.EqualFold(m[1], "title") && title == "" {
+				title = m[2]
+			}
+			continue
+		}
+		// # 开头的注释行
+		if line == "#" || strings.HasPrefix(line, "# ") {
+			continue
+		}
+
+		switch {
+		case orgHeadingRegexp.MatchString(line):
+			m := orgHeadingRegexp.FindStringSubmatch(line)
+			level := len(m[1])
+			if level > 6 {
+				level = 6
+			}
+			line = strings.Repeat("#", level) + " " + orgInline(m[2])
+		case orgTableRuleRegexp.MatchString(line):
+			line = strings.ReplaceAll(line, "+", "|")
+		default:
+			line = orgInline(orgOrderedRegexp.ReplaceAllString(line, "$1$2. "))
+		}
+		out = append(out, line)
+	}
+	if fence != "" {
+		out = append(out, fence)
+	}
+
+	md := strings.Join(out, "\n")
+	if title != "" {
+		fm, _ := yaml.Marshal(map[string]string{"title": title})
+		md = "---\n" + string(fm) + "---\n\n" + md
+	}
+	return []byte(md)
+}
+
+// isOrgCallout 判断 #+BEGIN_NOTE 等块是否对应提示块，QUOTE 块转换为普通引用
+func isOrgCallout(block string) bool {
+	_, ok := callouts[strings.ToLower(block)]
+	return ok && !strings.EqualFold(block, "quote")
+}
+
+// orgInline 转换一行中的行内标记与链接
+func orgInline(line string) string {
+	for _, e := range orgEmphasis[:2] {
+		line = e.re.ReplaceAllString(line, e.replace)
+	}
+	line = transformCodeSpans(line, func(text string) string {
+		for _, e := range orgEmphasis[2:] {
+			// 相邻的标记共用中间的空白，需要转换两次
+			text = e.re.ReplaceAllString(text, e.replace)
+			text = e.re.ReplaceAllString(text, e.replace)
+		}
+		return orgLinkRegexp.ReplaceAllStringFunc(text, orgLink)
+	})
+	return line
+}
+
+// orgLink 将 [[target][description]] 转换为 Markdown 链接，指向图片且没有描述时转换为图片
+func orgLink(m string) string {
+	sub := orgLinkRegexp.FindStringSubmatch(m)
+	target, desc := sub[1], sub[2]
+	target = strings.TrimPrefix(target, "file:")
+	if strings.HasPrefix(target, "*") {
+		// 指向本文标题的链接
+		heading := strings.TrimSpace(strings.TrimPrefix(target, "*"))
+		if desc == "" {
+			desc = heading
+		}
+		return fmt.Sprintf("[%s](#%s)", desc, blackfriday.SanitizedAnchorName(heading))
+	}
+	href := (&url.URL{Path: target}).EscapedPath()
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+		href = target
+	}
+	if desc == "" {
+		if imageRegexp.MatchString(strings.ToLower(target)) {
+			return fmt.Sprintf("![](%s)", href)
+		}
+		desc = target
+	}
+	return fmt.Sprintf("[%s](%s)", desc, href)
+}
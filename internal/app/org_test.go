@@ -0,0 +1,75 @@
+package app
+
+import "testing"
+
+func TestOrgInline(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"*bold* /italic/ +del+", "**bold** _italic_ ~~del~~"},
+		{"=code= and ~verb~", "`code` and `verb`"},
+		{"=*not bold*=", "`*not bold*`"},
+		{"*a* *b*", "**a** **b**"},
+		{"(*a*), /b/.", "(**a**), _b_."},
+		{"a*b*c 2*3*4", "a*b*c 2*3*4"},
+		{"path/to/file and 1/2/3", "path/to/file and 1/2/3"},
+		{"* not bold *", "* not bold *"},
+		{"[[https://example.com][Example]]", "[Example](https://example.com)"},
+		{"[[file:img/a.png]]", "![](img/a.png)"},
+		{"[[file:notes/a b.org][notes]]", "[notes](notes/a%20b.org)"},
+		{"[[*Install Steps]]", "[Install Steps](#install-steps)"},
+		{"[[*Install][see]]", "[see](#install)"},
+		{"[[mailto:a@example.com]]", "[mailto:a@example.com](mailto:a@example.com)"},
+		{"=[[no link]]=", "`[[no link]]`"},
+	}
+	for _, tt := range tests {
+		if got := orgInline(tt.line); got != tt.want {
+			t.Errorf("orgInline(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestOrgToMarkdown(t *testing.T) {
+	tests := []struct {
+		name, org, want string
+	}{
+		{"title", "#+TITLE: Guide\n#+AUTHOR: me\ntext", "---\ntitle: Guide\n---\n\ntext"},
+		{"first title wins", "#+title: A\n#+TITLE: B", "---\ntitle: A\n---\n\n"},
+		{"headings", "* TODO [#A] Install :setup:\n******* Deep", "# Install\n###### Deep"},
+		{"not a heading", "*bold* text", "**bold** text"},
+		{"comments", "# comment\n#\nkept", "kept"},
+		{"src block", "#+BEGIN_SRC go :exports code\n*x* = 1\n#+END_SRC", "```go\n*x* = 1\n```"},
+		{"example block", "#+begin_example\n/a/\n#+end_example", "```\n/a/\n```"},
+		{"unclosed src", "#+BEGIN_SRC sh\nls", "```sh\nls\n```"},
+		{"quote", "#+BEGIN_QUOTE\n*q*\n#+END_QUOTE", "> **q**"},
+		{"callout", "#+BEGIN_NOTE Title\n/n/\n#+END_NOTE", ":::note Title\n_n_\n:::"},
+		{"comment block", "#+BEGIN_COMMENT\nhidden\n#+END_COMMENT\nshown", "shown"},
+		{"export html", "#+BEGIN_EXPORT html\n<b>x</b>\n#+END_EXPORT", "<b>x</b>"},
+		{"export latex", "#+BEGIN_EXPORT latex\n\\x\n#+END_EXPORT\nafter", "after"},
+		{"mismatched end", "#+BEGIN_SRC\n#+END_QUOTE\n#+END_SRC", "```\n#+END_QUOTE\n```"},
+		{"drawer", "* H\n:PROPERTIES:\n:ID: 1\n:END:\nSCHEDULED: <2024-01-01>\nbody", "# H\nbody"},
+		{"table", "| a | b |\n|---+---|\n| 1 | 2 |", "| a | b |\n|---|---|\n| 1 | 2 |"},
+		{"ordered list", "1) one\n  2) two", "1. one\n  2. two"},
+		{"crlf", "* A\r\ntext", "# A\ntext"},
+	}
+	for _, tt := range tests {
+		if got := string(orgToMarkdown([]byte(tt.org))); got != tt.want {
+			t.Errorf("%s: orgToMarkdown(%q) = %q, want %q", tt.name, tt.org, got, tt.want)
+		}
+	}
+}
+
+func TestOrgTitle(t *testing.T) {
+	tests := []struct {
+		org, want string
+	}{
+		{"#+TITLE: Guide\n* Heading", "Guide"},
+		{"text\n* Heading", "Heading"},
+		{"text only", "name"},
+	}
+	for _, tt := range tests {
+		if got := orgTitle([]byte(tt.org), "name"); got != tt.want {
+			t.Errorf("orgTitle(%q) = %q, want %q", tt.org, got, tt.want)
+		}
+	}
+}
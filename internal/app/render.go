@@ -0,0 +1,266 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/russross/blackfriday/v2"
+)
+
+// markdownExtensions 解析 Markdown 时启用的扩展
+const markdownExtensions = blackfriday.CommonExtensions | blackfriday.Footnotes
+
+// Heading 文章大纲中的一个标题
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Rendered 文章的渲染结果
+type Rendered struct {
+	Title       string            `json:"title"` // 文章标题，未设置时为空
+	HTML        template.HTML     `json:"html"`
+	Outline     []Heading         `json:"outline"`
+	Stats       Stats             `json:"-"`
+	FrontMatter utils.FrontMatter `json:"-"`
+	Deps        map[string]int64  `json:"-"` // 嵌入的其他文件及其修改时间
+}
+
+// renderContext 单篇文章渲染过程中的状态
+type renderContext struct {
+	File     string     // 文章的文件路径
+	Path     string     // 文章的访问路径，如 ops/deploy
+	Wiki     *wikiIndex // wiki 链接解析索引
+	Title    string     // front matter 中的标题
+	title    string     // 作为标题的开头一级标题
+	outline  []Heading
+	ids      map[string]bool
+	callouts map[*blackfriday.Node]string // 提示块节点 -> 结束标签
+	deps     map[string]int64             // 嵌入的其他文件 -> 修改时间
+	cites    *citations                   // 文中的文献引用
+
+	shortcodes     map[string]string // 短代码等预先渲染的片段的占位符 -> 渲染结果
+	fragments      map[string]bool   // 已按文章策略清理的短代码内部内容的占位符
+	shortcodeNonce string
+}
+
+// uniqueID 生成不重复的标题 ID，重复时追加 -1、-2 ...
+func (c *renderContext) uniqueID(id string) string {
+	if id == "" {
+		id = "section"
+	}
+	candidate := id
+	for n := 1; c.ids[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", id, n)
+	}
+	c.ids[candidate] = true
+	return candidate
+}
+
+// articleRenderer 在 blackfriday 默认渲染的基础上处理标题锚点、提示块、相对链接等
+type articleRenderer struct {
+	*blackfriday.HTMLRenderer
+	ctx    *renderContext
+	nested bool // 是否在渲染短代码内部的内容
+}
+
+func (c *renderContext) newRenderer() *articleRenderer {
+	return &articleRenderer{
+		HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+			Flags:                      blackfriday.FootnoteReturnLinks,
+			FootnoteReturnLinkContents: "↩",
+		}),
+		ctx: c,
+	}
+}
+
+func (r *articleRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	switch node.Type {
+	case blackfriday.Heading:
+		if entering {
+			// 开头的一级标题作为文章标题显示在页面顶部，正文中不再重复
+			if r.isTitle(node) {
+				r.ctx.title = nodeText(node)
+				return blackfriday.SkipChildren
+			}
+			return r.renderHeading(w, node)
+		}
+	case blackfriday.CodeBlock:
+		if r.renderDataBlock(w, node) {
+			return blackfriday.GoToNext
+		}
+	case blackfriday.BlockQuote:
+		if r.renderCallout(w, node, entering) {
+			return blackfriday.GoToNext
+		}
+	case blackfriday.Link:
+		// 脚注引用的目标为脚注标签，不做解析
+		if entering && node.NoteID == 0 {
+			node.LinkData.Destination = []byte(resolveLink(r.ctx.Path, string(node.LinkData.Destination)))
+		}
+	case blackfriday.Image:
+		if entering {
+			node.LinkData.Destination = []byte(resolveLink(r.ctx.Path, string(node.LinkData.Destination)))
+			if r.renderImage(w, node) {
+				return blackfriday.SkipChildren
+			}
+		}
+	}
+	return r.HTMLRenderer.RenderNode(w, node, entering)
+}
+
+// renderHeading 为标题生成 ID 与锚点，并记录到文章大纲中
+func (r *articleRenderer) renderHeading(w io.Writer, node *blackfriday.Node) blackfriday.WalkStatus {
+	title := nodeText(node)
+	id := node.HeadingID
+	if id == "" {
+		id = blackfriday.SanitizedAnchorName(title)
+	}
+	node.HeadingID = r.ctx.uniqueID(id)
+	r.ctx.outline = append(r.ctx.outline, Heading{Level: node.Level, ID: node.HeadingID, Title: title})
+
+	status := r.HTMLRenderer.RenderNode(w, node, true)
+	fmt.Fprintf(w, `<a class="anchor" href="#%s"><span class="octicon octicon-link"></span></a>`, html.EscapeString(node.HeadingID))
+	return status
+}
+
+// isTitle 判断标题是否为文章开头、与文章标题一致的一级标题
+func (r *articleRenderer) isTitle(node *blackfriday.Node) bool {
+	if r.nested || node.Level != 1 || node.Prev != nil || node.Parent == nil || node.Parent.Type != blackfriday.Document {
+		return false
+	}
+	return r.ctx.Title == "" || strings.EqualFold(nodeText(node), r.ctx.Title)
+}
+
+// nodeText 节点内的纯文本
+func nodeText(node *blackfriday.Node) string {
+	var buf strings.Builder
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (n.Type == blackfriday.Text || n.Type == blackfriday.Code) {
+			buf.Write(n.Literal)
+		}
+		return blackfriday.GoToNext
+	})
+	return strings.TrimSpace(buf.String())
+}
+
+// tocRegexp 匹配文中单独一行的 [toc] 标记
+var tocRegexp = regexp.MustCompile(`(?i)<p>` + regexp.QuoteMeta(TocPrefix) + `</p>`)
+
+// renderToc 根据大纲生成嵌套的目录
+func renderToc(outline []Heading) string {
+	if len(outline) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString(`<div class="toc">`)
+	depth := 0
+	base := outline[0].Level
+	for _, h := range outline {
+		if h.Level < base {
+			base = h.Level
+		}
+	}
+	for i, h := range outline {
+		level := h.Level - base + 1
+		switch {
+		case level > depth:
+			for ; depth < level; depth++ {
+				buf.WriteString("<ul><li>")
+			}
+		case level < depth:
+			for ; depth > level; depth-- {
+				buf.WriteString("</li></ul>")
+			}
+			buf.WriteString("</li><li>")
+		case i > 0:
+			buf.WriteString("</li><li>")
+		}
+		fmt.Fprintf(&buf, `<a href="#%s">%s</a>`, html.EscapeString(h.ID), html.EscapeString(h.Title))
+	}
+	for ; depth > 0; depth-- {
+		buf.WriteString("</li></ul>")
+	}
+	buf.WriteString(`</div>`)
+	return buf.String()
+}
+
+// newRenderContext 创建文章 file 的渲染状态，f 为文章的访问路径
+func newRenderContext(file, f string, wiki *wikiIndex) *renderContext {
+	return &renderContext{
+		File:     file,
+		Path:     f,
+		Wiki:     wiki,
+		ids:      make(map[string]bool),
+		callouts: make(map[*blackfriday.Node]string),
+		deps:     make(map[string]int64),
+	}
+}
+
+// render 渲染 Markdown 内容，其他格式的文章可转换为 Markdown 后调用
+func (c *renderContext) render(content []byte) *Rendered {
+	file, f, wiki := c.File, c.Path, c.Wiki
+	fm, body := utils.ParseFrontMatter(content)
+	c.Title = strings.TrimSpace(fm.Title)
+	strs := c.expandIncludes(string(body), file, nil)
+	strs = c.expandEmbeds(strs)
+	stats := textStats([]byte(strs))
+	strs = c.renderCitations(strs)
+	strs = separateQuotes(convertContainers(strs))
+	strs = wiki.renderWikiLinks(strs, f)
+
+	// fix windows \r\n
+	unix := strings.ReplaceAll(strs, "\r\n", "\n")
+	unix = c.expandShortcodes(unix)
+
+	unsafe := blackfriday.Run([]byte(unix), blackfriday.WithRenderer(c.newRenderer()), blackfriday.WithExtensions(markdownExtensions))
+
+	// 文中任意位置的 [toc] 替换为目录
+	toc := renderToc(c.outline)
+	unsafe = tocRegexp.ReplaceAllFunc(unsafe, func([]byte) []byte {
+		return []byte(toc)
+	})
+
+	// 参考文献列在脚注之前
+	if refs := c.renderReferences(); refs != "" {
+		if i := bytes.Index(unsafe, []byte(`<div class="footnotes">`)); i >= 0 {
+			unsafe = append(unsafe[:i], append([]byte(refs+"\n\n"), unsafe[i:]...)...)
+		} else {
+			unsafe = append(unsafe, refs...)
+		}
+	}
+
+	// 按文章所在目录的策略清理HTML，短代码的渲染结果按短代码的策略单独清理
+	html := c.replaceShortcodes(string(sanitize(f, unsafe)), func(s string) string {
+		return sanitizeShortcode(f, s)
+	})
+
+	title := c.Title
+	if title == "" {
+		title = c.title
+	}
+	for _, h := range c.outline {
+		if title != "" {
+			break
+		}
+		if h.Level == 1 {
+			title = h.Title
+		}
+	}
+
+	return &Rendered{
+		Title:       title,
+		HTML:        template.HTML(html),
+		Outline:     c.outline,
+		Stats:       stats,
+		FrontMatter: fm,
+		Deps:        c.deps,
+	}
+}
@@ -0,0 +1,229 @@
+package app
+
+import (
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// 预设的 HTML 清理策略
+const (
+	PresetStrict  = "strict"  // bluemonday.UGCPolicy，仅允许渲染器自身生成的标记
+	PresetUGC     = "ugc"     // 在 strict 的基础上允许 <span style>，默认值
+	PresetTrusted = "trusted" // 不做清理，允许任意 HTML
+)
+
+// sanitizerPolicies 各预设对应的清理策略，trusted 为 nil
+var sanitizerPolicies map[string]*bluemonday.Policy
+
+// shortcodePolicy 短代码输出的清理策略
+var shortcodePolicy *bluemonday.Policy
+
+// sanitizerOverride 目录级别的策略覆盖
+type sanitizerOverride struct {
+	Dir    string
+	Preset string
+}
+
+var sanitizerOverrides []sanitizerOverride
+
+// initSanitizer 根据配置生成各预设的清理策略
+func initSanitizer() {
+	sanitizerPolicies = map[string]*bluemonday.Policy{
+		PresetStrict:  newPolicy(PresetStrict),
+		PresetUGC:     newPolicy(PresetUGC),
+		PresetTrusted: nil,
+	}
+	shortcodePolicy = newShortcodePolicy()
+	if _, ok := sanitizerPolicies[Sanitizer.Preset]; !ok {
+		if Sanitizer.Preset != "" {
+			log.Printf("Unknown sanitizer preset %q, use %q", Sanitizer.Preset, PresetUGC)
+		}
+		Sanitizer.Preset = PresetUGC
+	}
+
+	sanitizerOverrides = sanitizerOverrides[:0]
+	for _, o := range Sanitizer.Overrides {
+		dir, preset, ok := strings.Cut(o, ":")
+		dir = strings.Trim(strings.TrimSpace(dir), "/")
+		preset = strings.TrimSpace(preset)
+		if _, known := sanitizerPolicies[preset]; !ok || !known || dir == "" {
+			log.Printf("Invalid sanitizer override %q, expect dir:preset", o)
+			continue
+		}
+		sanitizerOverrides = append(sanitizerOverrides, sanitizerOverride{Dir: dir, Preset: preset})
+	}
+}
+
+// newPolicy 生成预设对应的策略，ugc 附加配置中额外允许的标签与属性
+func newPolicy(preset string) *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	allowRenderMarkup(p)
+
+	// 配置中额外允许的标签、属性与 iframe 只用于 ugc，strict 保持不变
+	if preset == PresetUGC {
+		p.AllowElements("span")                  // 只允许<span>标签
+		p.AllowAttrs("style").OnElements("span") // 在<span>上允许使用style属性
+		allowConfigured(p, Sanitizer.Elements, Sanitizer.Attrs, Sanitizer.IframeSrc)
+	}
+
+	return p
+}
+
+// newShortcodePolicy 短代码输出的清理策略，在 ugc 的基础上允许视频、音频、
+// iframe 嵌入以及标签页等组件常用的标签与属性
+func newShortcodePolicy() *bluemonday.Policy {
+	p := newPolicy(PresetUGC)
+	p.AllowElements("video", "audio", "source", "track", "figure", "figcaption", "button", "details", "summary")
+	p.AllowAttrs("controls", "autoplay", "muted", "loop", "playsinline", "preload", "poster", "width", "height").OnElements("video", "audio")
+	p.AllowAttrs("src").OnElements("video", "audio", "source", "track")
+	p.AllowAttrs("type").OnElements("source", "button")
+	p.AllowAttrs("kind", "srclang", "label").OnElements("track")
+	p.AllowAttrs("open").OnElements("details")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\s-]+$`)).Globally()
+	p.AllowAttrs("role", "aria-label", "aria-selected", "aria-controls", "aria-hidden", "aria-expanded").Globally()
+	p.AllowDataAttributes()
+	allowConfigured(p, Sanitizer.ShortcodeElements, Sanitizer.ShortcodeAttrs, Sanitizer.ShortcodeIframeSrc)
+	return p
+}
+
+// allowConfigured 允许配置中额外指定的标签、属性与 iframe 地址
+func allowConfigured(p *bluemonday.Policy, elements, attrs, iframeSrc []string) {
+	if len(elements) > 0 {
+		p.AllowElements(elements...)
+	}
+	for _, spec := range attrs {
+		attrList, elementList, _ := strings.Cut(spec, ":")
+		names := splitList(attrList)
+		if len(names) == 0 {
+			continue
+		}
+		if tags := splitList(elementList); len(tags) > 0 {
+			p.AllowAttrs(names...).OnElements(tags...)
+		} else {
+			p.AllowAttrs(names...).Globally()
+		}
+	}
+	if re := iframeSrcRegexp(iframeSrc); re != nil {
+		p.AllowElements("iframe")
+		p.AllowAttrs("src").Matching(re).OnElements("iframe")
+		p.AllowAttrs("width", "height", "frameborder", "allow", "allowfullscreen", "loading", "title").OnElements("iframe")
+	}
+}
+
+// iframeSrcRegexp 匹配允许的 iframe 地址：协议与域名完全相同，路径以配置的路径开头且在 / 处结束，
+// 如 https://www.youtube.com 不匹配 https://www.youtube.com.evil.example/，
+// https://example.com/embed 不匹配 https://example.com/embedded。没有有效地址时返回 nil
+func iframeSrcRegexp(srcs []string) *regexp.Regexp {
+	var patterns []string
+	for _, src := range srcs {
+		u, err := url.Parse(strings.TrimSpace(src))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+			log.Printf("Invalid iframe source %q, expect http(s)://host/path", src)
+			continue
+		}
+		prefix := regexp.QuoteMeta(strings.ToLower(u.Scheme+"://"+u.Host) + u.EscapedPath())
+		if strings.HasSuffix(u.Path, "/") {
+			patterns = append(patterns, prefix)
+		} else {
+			patterns = append(patterns, prefix+`(?:[/?#]|$)`)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`^(?:` + strings.Join(patterns, "|") + `)`)
+}
+
+// allowRenderMarkup 允许渲染器自身生成的标记，如标题锚点、wiki 链接、提示块、脚注与引用、代码语言、响应式图片、内嵌图片与 Notebook 输出等
+func allowRenderMarkup(p *bluemonday.Policy) {
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(wikilink( wikilink-broken)?|anchor)$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^octicon octicon-link$`)).OnElements("span")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^toc$`)).OnElements("div")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^callout callout-[a-z]+$`)).OnElements("div", "details")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^callout-title$`)).OnElements("p", "summary")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^fa fa-[a-z-]+$`)).OnElements("i")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(citation-ref|footnote-return)$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(citation|citation-missing)$`)).OnElements("span")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnote-ref$`)).OnElements("sup")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(footnotes|references)$`)).OnElements("div")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^references-title$`)).OnElements("p")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("srcset").Matching(regexp.MustCompile(`^/[^\s,]+ \d+w(, /[^\s,]+ \d+w)*$`)).OnElements("img")
+	p.AllowAttrs("sizes").Matching(regexp.MustCompile(`^[\w\s(),:-]+$`)).OnElements("img")
+	p.AllowAttrs("loading").Matching(regexp.MustCompile(`^(lazy|eager)$`)).OnElements("img")
+	p.AllowAttrs("decoding").Matching(regexp.MustCompile(`^(async|sync|auto)$`)).OnElements("img")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(data-table-wrapper|chart)$`)).OnElements("div")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^data-table$`)).OnElements("table")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^nb-output( nb-stream( nb-stderr)?| nb-error)?$`)).OnElements("pre")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^nb-output nb-(html|markdown|image)$`)).OnElements("div")
+	allowChartMarkup(p)
+	// Notebook 的图片输出与附件以 data URI 内嵌
+	p.AllowDataURIImages()
+	// 标题 ID 允许中文等非 ASCII 字符
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_:.-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+}
+
+// allowChartMarkup 允许服务端生成的 SVG 图表，只允许绘图所需的标签，属性取值严格限制
+func allowChartMarkup(p *bluemonday.Policy) {
+	number := regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+	shapes := []string{"rect", "line", "polyline", "path", "circle", "text"}
+	p.AllowElements(append(shapes, "svg", "title")...)
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^chart-svg chart-(line|bar|pie)$`)).OnElements("svg")
+	p.AllowAttrs("viewbox").Matching(regexp.MustCompile(`^0 0 \d+ \d+$`)).OnElements("svg")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^img$`)).OnElements("svg")
+	p.AllowAttrs("aria-label").OnElements("svg")
+	p.AllowAttrs("width", "height").Matching(number).OnElements("svg", "rect")
+	p.AllowAttrs("x", "y").Matching(number).OnElements("rect", "text")
+	p.AllowAttrs("x1", "y1", "x2", "y2").Matching(number).OnElements("line")
+	p.AllowAttrs("cx", "cy", "r").Matching(number).OnElements("circle")
+	p.AllowAttrs("points").Matching(regexp.MustCompile(`^[\d., -]+$`)).OnElements("polyline")
+	p.AllowAttrs("d").Matching(regexp.MustCompile(`^[MLAZ\d. -]+$`)).OnElements("path")
+	p.AllowAttrs("fill", "stroke").Matching(regexp.MustCompile(`^(none|currentColor|#[0-9a-fA-F]{6})$`)).OnElements(shapes...)
+	p.AllowAttrs("stroke-width", "stroke-opacity", "font-size").Matching(number).OnElements(shapes...)
+	p.AllowAttrs("text-anchor").Matching(regexp.MustCompile(`^(start|middle|end)$`)).OnElements("text")
+}
+
+// policyFor 返回文章适用的清理策略，目录覆盖优先，匹配最长的目录；返回 nil 表示不清理
+func policyFor(f string) *bluemonday.Policy {
+	if sanitizerPolicies == nil {
+		initSanitizer()
+	}
+	preset, matched := Sanitizer.Preset, ""
+	for _, o := range sanitizerOverrides {
+		if (f == o.Dir || strings.HasPrefix(f, o.Dir+"/")) && len(o.Dir) > len(matched) {
+			preset, matched = o.Preset, o.Dir
+		}
+	}
+	return sanitizerPolicies[preset]
+}
+
+// sanitize 按文章所在目录的策略清理 HTML
+func sanitize(f string, unsafe []byte) []byte {
+	if p := policyFor(f); p != nil {
+		return p.SanitizeBytes(unsafe)
+	}
+	return unsafe
+}
+
+// sanitizeShortcode 清理短代码的输出，文章所在目录为 trusted 策略时不清理
+func sanitizeShortcode(f string, unsafe string) string {
+	if policyFor(f) == nil {
+		return unsafe
+	}
+	return shortcodePolicy.Sanitize(unsafe)
+}
+
+// splitList 拆分逗号分隔的列表
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gaowei-space/markdown-blog/internal/types"
+)
+
+// withSanitizer 使用指定的配置重新生成清理策略，测试结束后恢复
+func withSanitizer(t *testing.T, s types.Sanitizer) {
+	old := Sanitizer
+	Sanitizer = s
+	initSanitizer()
+	t.Cleanup(func() {
+		Sanitizer = old
+		initSanitizer()
+	})
+}
+
+func TestIframeSrcRegexp(t *testing.T) {
+	re := iframeSrcRegexp([]string{"https://www.youtube.com", "https://player.example.com/embed", "https://v.example.org/video/"})
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"https://www.youtube.com/embed/abc", true},
+		{"https://www.youtube.com", true},
+		{"https://www.youtube.com?list=1", true},
+		{"https://www.youtube.com.evil.example/embed/abc", false},
+		{"https://www.youtube.com@evil.example/", false},
+		{"http://www.youtube.com/embed/abc", false},
+		{"https://player.example.com/embed/1", true},
+		{"https://player.example.com/embed", true},
+		{"https://player.example.com/embedded/1", false},
+		{"https://v.example.org/video/1", true},
+		{"https://v.example.org/videos/1", false},
+		{"javascript:alert(1)", false},
+	}
+	for _, tt := range tests {
+		if got := re.MatchString(tt.src); got != tt.want {
+			t.Errorf("iframe src %q allowed = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestIframeSrcRegexpInvalid(t *testing.T) {
+	if re := iframeSrcRegexp([]string{"www.youtube.com", "javascript:alert(1)", "ftp://example.com/", ""}); re != nil {
+		t.Errorf("invalid iframe sources produced %s, want nil", re)
+	}
+}
+
+func TestSanitizerPresets(t *testing.T) {
+	withSanitizer(t, types.Sanitizer{
+		Preset:    PresetUGC,
+		Elements:  []string{"kbd"},
+		IframeSrc: []string{"https://www.youtube.com/embed/"},
+		Overrides: []string{"internal:trusted", "internal/public:strict", "notes:unknown", ":strict"},
+	})
+	src := []byte(`<kbd>K</kbd><span style="color:red">s</span><iframe src="https://www.youtube.com/embed/x"></iframe><iframe src="https://evil.example/"></iframe><script>alert(1)</script>`)
+	tests := []struct {
+		path    string
+		want    []string
+		notWant []string
+	}{
+		{"guide/a", []string{"<kbd>K</kbd>", `style="color:red"`, `src="https://www.youtube.com/embed/x"`}, []string{"evil.example", "<script>"}},
+		{"internal/a", []string{"<script>", "evil.example"}, nil},
+		{"internal/public/a", []string{"K"}, []string{"<kbd>", "style=", "<iframe", "<script>"}},
+		{"internalx/a", []string{"<kbd>K</kbd>"}, []string{"<script>"}},
+		{"notes/a", []string{"<kbd>K</kbd>"}, []string{"<script>"}},
+	}
+	for _, tt := range tests {
+		got := string(sanitize(tt.path, src))
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("sanitize(%q) = %q, want it to contain %q", tt.path, got, w)
+			}
+		}
+		for _, w := range tt.notWant {
+			if strings.Contains(got, w) {
+				t.Errorf("sanitize(%q) = %q, want it without %q", tt.path, got, w)
+			}
+		}
+	}
+}
+
+func TestUnknownPresetFallsBackToUGC(t *testing.T) {
+	withSanitizer(t, types.Sanitizer{Preset: "loose"})
+	if Sanitizer.Preset != PresetUGC {
+		t.Errorf("preset = %q, want %q", Sanitizer.Preset, PresetUGC)
+	}
+}
@@ -0,0 +1,302 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/bindata/views"
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/russross/blackfriday/v2"
+)
+
+// ShortcodeDir 短代码模板所在目录，位于 MdDir 或主题模板目录下
+const ShortcodeDir = "shortcodes"
+
+// 匹配 {{< name key="value" >}}、{{< name >}} 与 {{< /name >}}
+var shortcodeRegexp = regexp.MustCompile(`\{\{<\s*(/?)\s*([\w-]+)(.*?)\s*(/?)\s*>\}\}`)
+
+// 匹配短代码参数：key="value"、key='value'、key=value 或位置参数
+var shortcodeArgRegexp = regexp.MustCompile(`(?:([\w-]+)=)?("(?:[^"\\]|\\.)*"|'[^']*'|[^\s"']+)`)
+
+// 短代码标签在展开过程中的占位标记
+var shortcodeMarkerRegexp = regexp.MustCompile("\x00(\\d+)\x00")
+
+// shortcodeTag 文中的一个短代码标签
+type shortcodeTag struct {
+	Name    string
+	Args    string
+	Closing bool   // {{< /name >}}
+	Raw     string // 标签原文
+}
+
+// ShortcodeData 短代码模板的数据
+type ShortcodeData struct {
+	Name   string            // 短代码名称
+	Args   map[string]string // 命名参数
+	Params []string          // 位置参数
+	Inner  template.HTML     // 成对短代码内部的内容，渲染为 HTML
+	Page   string            // 当前文章的访问路径
+}
+
+// Get 按名称取命名参数，或按序号取位置参数
+func (d ShortcodeData) Get(key string) string {
+	if v, ok := d.Args[key]; ok {
+		return v
+	}
+	if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(d.Params) {
+		return d.Params[i]
+	}
+	return ""
+}
+
+// IsSet 判断是否设置了参数
+func (d ShortcodeData) IsSet(key string) bool {
+	_, ok := d.Args[key]
+	return ok
+}
+
+// shortcodeFuncs 短代码模板中可用的函数
+var shortcodeFuncs = template.FuncMap{
+	"default": func(def, v string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// expandShortcodes 将短代码替换为占位符，渲染结果记录在 c.shortcodes 中，
+// 待整篇文章渲染并清理后再替换回来。代码块中的短代码保持原样
+func (c *renderContext) expandShortcodes(src string) string {
+	var tags []shortcodeTag
+	marked := transformMarkdown(src, func(text string) string {
+		return shortcodeRegexp.ReplaceAllStringFunc(text, func(m string) string {
+			sub := shortcodeRegexp.FindStringSubmatch(m)
+			if sub[2] == "include" {
+				return m
+			}
+			tags = append(tags, shortcodeTag{Name: sub[2], Args: sub[3], Closing: sub[1] == "/", Raw: m})
+			return fmt.Sprintf("\x00%d\x00", len(tags)-1)
+		})
+	})
+	if len(tags) == 0 {
+		return src
+	}
+	return c.expandShortcodeTags(marked, tags)
+}
+
+// expandShortcodeTags 展开 text 中最外层的短代码，内部的短代码在渲染内部内容时递归展开
+func (c *renderContext) expandShortcodeTags(text string, tags []shortcodeTag) string {
+	var out strings.Builder
+	markers := shortcodeMarkerRegexp.FindAllStringSubmatchIndex(text, -1)
+	last := 0
+	for i := 0; i < len(markers); i++ {
+		m := markers[i]
+		tag := tags[atoi(text[m[2]:m[3]])]
+		out.WriteString(text[last:m[0]])
+		last = m[1]
+		if tag.Closing {
+			out.WriteString(tag.Raw)
+			continue
+		}
+
+		// 查找对应的结束标签，允许同名短代码嵌套
+		end, depth := -1, 0
+		for j := i + 1; j < len(markers) && end < 0; j++ {
+			t := tags[atoi(text[markers[j][2]:markers[j][3]])]
+			switch {
+			case t.Name != tag.Name:
+			case !t.Closing:
+				depth++
+			case depth > 0:
+				depth--
+			default:
+				end = j
+			}
+		}
+
+		var inner *string
+		block := isOwnLine(text, m[0], m[1])
+		if end >= 0 {
+			content := text[m[1]:markers[end][0]]
+			inner = &content
+			block = block || isOwnLine(text, markers[end][0], markers[end][1])
+			last = markers[end][1]
+			i = end
+		}
+		out.WriteString(c.placeholder(c.renderShortcode(tag, inner, block, tags), block))
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// placeholder 记录已渲染的 HTML 片段并返回其占位符，block 表示片段单独成段
+func (c *renderContext) placeholder(output string, block bool) string {
+	if c.shortcodes == nil {
+		c.shortcodes = make(map[string]string)
+	}
+	token := fmt.Sprintf("shortcode%sx%dx", c.nonce(), len(c.shortcodes))
+	c.shortcodes[token] = output
+	if block {
+		token = "\n\n" + token + "\n\n"
+	}
+	return token
+}
+
+// renderShortcode 执行短代码模板，inner 为成对短代码内部的 Markdown，
+// block 表示短代码单独成段，否则内部内容按行内元素渲染
+func (c *renderContext) renderShortcode(tag shortcodeTag, inner *string, block bool, tags []shortcodeTag) string {
+	tmpl, err := c.shortcodeTemplate(tag.Name)
+	if err != nil {
+		return shortcodeError(tag.Name, err, block)
+	}
+	data := ShortcodeData{Name: tag.Name, Args: make(map[string]string), Page: c.Path}
+	for _, m := range shortcodeArgRegexp.FindAllStringSubmatch(tag.Args, -1) {
+		v := m[2]
+		if strings.HasPrefix(v, `"`) {
+			if s, err := strconv.Unquote(v); err == nil {
+				v = s
+			}
+		} else if strings.HasPrefix(v, `'`) {
+			v = strings.Trim(v, `'`)
+		}
+		if m[1] != "" {
+			data.Args[m[1]] = v
+		} else {
+			data.Params = append(data.Params, v)
+		}
+	}
+	if inner != nil {
+		// 内部内容按文章的策略清理，短代码的策略只用于模板自身的标记
+		fragment := c.renderFragment(c.expandShortcodeTags(*inner, tags))
+		if !block {
+			fragment = unwrapParagraph(fragment)
+		}
+		data.Inner = template.HTML(c.fragmentPlaceholder(string(sanitize(c.Path, []byte(fragment)))))
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return shortcodeError(tag.Name, err, block)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// renderFragment 渲染短代码内部的 Markdown，其中已展开的短代码保留为占位符
+func (c *renderContext) renderFragment(src string) string {
+	renderer := c.newRenderer()
+	renderer.nested = true
+	return string(blackfriday.Run([]byte(src), blackfriday.WithRenderer(renderer), blackfriday.WithExtensions(markdownExtensions)))
+}
+
+// shortcodeTemplate 查找短代码模板，MdDir 下的模板优先于主题中的模板
+func (c *renderContext) shortcodeTemplate(name string) (*template.Template, error) {
+	file := filepath.Join(MdDir, ShortcodeDir, name+".html")
+	c.addDep(file)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		content, err = readThemeFile(ShortcodeDir + "/" + name + ".html")
+		if err != nil {
+			return nil, fmt.Errorf("shortcode template not found")
+		}
+	}
+	return template.New(name).Funcs(shortcodeFuncs).Parse(string(content))
+}
+
+// readThemeFile 读取主题模板目录中的文件
+func readThemeFile(name string) ([]byte, error) {
+	var fs http.FileSystem = http.Dir("./web/views")
+	if Env == "prod" {
+		fs = views.AssetFile()
+	}
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// replaceShortcodes 将占位符替换为短代码的渲染结果，单独成段的占位符替换整个段落。
+// 渲染结果中可能包含内部内容的占位符，逐层替换直到没有占位符。
+// sanitizeFn 不为空时用于清理渲染结果，已按文章策略清理的内部内容除外
+func (c *renderContext) replaceShortcodes(out string, sanitizeFn func(string) string) string {
+	for replaced := true; replaced; {
+		replaced = false
+		for token, output := range c.shortcodes {
+			if !strings.Contains(out, token) {
+				continue
+			}
+			if sanitizeFn != nil && !c.fragments[token] {
+				output = sanitizeFn(output)
+			}
+			out = strings.ReplaceAll(out, "<p>"+token+"</p>", output)
+			out = strings.ReplaceAll(out, token, output)
+			replaced = true
+		}
+	}
+	return out
+}
+
+// fragmentPlaceholder 记录已按文章策略清理的短代码内部内容并返回其占位符，替换时不再清理
+func (c *renderContext) fragmentPlaceholder(output string) string {
+	if c.fragments == nil {
+		c.fragments = make(map[string]bool)
+	}
+	token := c.placeholder(output, false)
+	c.fragments[token] = true
+	return token
+}
+
+// nonce 占位符中的随机部分，避免与正文冲突
+func (c *renderContext) nonce() string {
+	if c.shortcodeNonce == "" {
+		c.shortcodeNonce = utils.MD5(c.File)[:8]
+	}
+	return c.shortcodeNonce
+}
+
+// isOwnLine 判断 text[start:end] 是否单独占据一行
+func isOwnLine(text string, start, end int) bool {
+	before := text[:start]
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
+	}
+	after := text[end:]
+	if i := strings.IndexByte(after, '\n'); i >= 0 {
+		after = after[:i]
+	}
+	return strings.TrimSpace(before) == "" && strings.TrimSpace(after) == ""
+}
+
+// unwrapParagraph 去掉只有一个段落的 HTML 外层的 <p>
+func unwrapParagraph(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "<p>") && strings.HasSuffix(trimmed, "</p>") && strings.Count(trimmed, "<p>") == 1 {
+		return strings.TrimSuffix(strings.TrimPrefix(trimmed, "<p>"), "</p>")
+	}
+	return s
+}
+
+// shortcodeError 短代码渲染失败时在文中显示的提示
+func shortcodeError(name string, err error, block bool) string {
+	if !block {
+		return fmt.Sprintf(`<span class="shortcode-error">%s: %s</span>`, html.EscapeString(name), html.EscapeString(err.Error()))
+	}
+	return fmt.Sprintf(`<div class="callout callout-caution"><p class="callout-title"><i class="fa %s"></i> Shortcode failed</p><p><code>%s</code>: %s</p></div>`,
+		callouts["caution"].Icon, html.EscapeString(name), html.EscapeString(err.Error()))
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
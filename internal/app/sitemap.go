@@ -0,0 +1,165 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/kataras/iris/v12"
+)
+
+var (
+	BaseURL        string   // 站点地址，如 https://example.com，为空时按请求的地址生成
+	RobotsDisallow []string // robots.txt 中禁止抓取的路径
+)
+
+// sitemapLimit 单个 sitemap 的最大地址数量，超过时生成 sitemap 索引
+const sitemapLimit = 50000
+
+// siteURL 返回站点地址，未设置 base-url 时按请求的协议与域名生成
+func siteURL(ctx iris.Context) string {
+	if BaseURL != "" {
+		return strings.TrimSuffix(BaseURL, "/")
+	}
+	scheme := "http"
+	if ctx.Request().TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+	return scheme + "://" + ctx.Host()
+}
+
+// absURL 将站点内的访问路径转换为完整地址，路径按 URL 规则转义
+func absURL(site, key string) string {
+	return site + (&url.URL{Path: "/" + strings.TrimPrefix(key, "/")}).EscapedPath()
+}
+
+// sitemapURL sitemap 中的一个地址
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapEntries 按导航的顺序列出首页、文章与有页面的目录，不含草稿与 robots.txt 禁止抓取的路径。
+// 修改时间取索引中记录的时间
+func sitemapEntries(site string) []sitemapURL {
+	var modTimes map[string]time.Time
+	if indexer != nil {
+		modTimes = indexer.ModTimes()
+	}
+	lastMod := func(file string) string {
+		if t, ok := modTimes[file]; ok && !t.IsZero() {
+			return t.Format(time.RFC3339)
+		}
+		return ""
+	}
+
+	entries := []sitemapURL{{Loc: site + "/"}}
+	add := func(key, file string) {
+		if file == "" || readBrief(file).FrontMatter.Draft || robotsDisallowed("/"+key) {
+			return
+		}
+		entries = append(entries, sitemapURL{Loc: absURL(site, key), LastMod: lastMod(file)})
+	}
+	var walk func(node *utils.Node)
+	walk = func(node *utils.Node) {
+		for _, child := range node.Children {
+			switch {
+			case child.IsDir:
+				if child.Index {
+					add(nodeKey(child), utils.DirIndex(child.Path))
+				}
+				walk(child)
+			case child.IsPage():
+				add(nodeKey(child), child.Path)
+			}
+		}
+	}
+	for _, root := range getTree().Children {
+		walk(root)
+	}
+	return entries
+}
+
+// robotsDisallowed 判断访问路径是否被 robots.txt 禁止抓取
+func robotsDisallowed(link string) bool {
+	for _, rule := range RobotsDisallow {
+		if rule = strings.TrimSpace(rule); rule != "" && strings.HasPrefix(link, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// sitemapHandler 输出 sitemap.xml。地址超过 sitemapLimit 时输出 sitemap 索引，
+// 各部分通过 /sitemap.xml?page=N 访问
+func sitemapHandler(ctx iris.Context) {
+	site := siteURL(ctx)
+	entries := sitemapEntries(site)
+	pages := (len(entries) + sitemapLimit - 1) / sitemapLimit
+
+	var v interface{}
+	page := ctx.URLParamIntDefault("page", 0)
+	switch {
+	case pages <= 1 && page <= 1:
+		v = sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: entries}
+	case page == 0:
+		index := sitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+		for i := 1; i <= pages; i++ {
+			index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: fmt.Sprintf("%s/sitemap.xml?page=%d", site, i)})
+		}
+		v = index
+	case page <= pages:
+		end := page * sitemapLimit
+		if end > len(entries) {
+			end = len(entries)
+		}
+		v = sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: entries[(page-1)*sitemapLimit : end]}
+	default:
+		ctx.StatusCode(404)
+		return
+	}
+
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		ctx.StatusCode(500)
+		return
+	}
+	ctx.ContentType("application/xml")
+	ctx.Write([]byte(xml.Header))
+	ctx.Write(out)
+}
+
+// robotsHandler 输出 robots.txt，包括配置中禁止抓取的路径与 sitemap 的地址
+func robotsHandler(ctx iris.Context) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	if len(RobotsDisallow) == 0 {
+		b.WriteString("Disallow:\n")
+	}
+	for _, rule := range RobotsDisallow {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			fmt.Fprintf(&b, "Disallow: %s\n", rule)
+		}
+	}
+	fmt.Fprintf(&b, "\nSitemap: %s/sitemap.xml\n", siteURL(ctx))
+	ctx.ContentType("text/plain")
+	ctx.WriteString(b.String())
+}
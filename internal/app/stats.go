@@ -0,0 +1,113 @@
+package app
+
+import (
+	"math"
+	"os"
+	"time"
+	"unicode"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/russross/blackfriday/v2"
+)
+
+// 阅读速度：中日韩文字每分钟字数与其他语言每分钟词数
+const (
+	cjkPerMinute  = 300
+	wordPerMinute = 200
+)
+
+// Stats 文章字数与预计阅读时间
+type Stats struct {
+	Words       int `json:"words"`       // 字数，中日韩文字按字计，其他语言按词计
+	ReadingTime int `json:"readingTime"` // 预计阅读时间，单位分钟
+}
+
+// ArticleMeta 文章的统计信息与更新时间
+type ArticleMeta struct {
+	Stats
+	ModTime time.Time  `json:"modTime"`           // 文件最后修改时间
+	Updated *time.Time `json:"updated,omitempty"` // front matter 中的 updated
+}
+
+// textStats 统计 Markdown 正文的字数与阅读时间，不计代码块与标记符号
+func textStats(content []byte) Stats {
+	var cjk, words int
+	md := blackfriday.New(blackfriday.WithExtensions(markdownExtensions))
+	md.Parse(content).Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering {
+			return blackfriday.GoToNext
+		}
+		switch node.Type {
+		case blackfriday.CodeBlock, blackfriday.HTMLBlock:
+			return blackfriday.SkipChildren
+		case blackfriday.Text, blackfriday.Code:
+			c, w := countWords(string(node.Literal))
+			cjk += c
+			words += w
+		}
+		return blackfriday.GoToNext
+	})
+	return newStats(cjk, words)
+}
+
+// plainStats 统计纯文本的字数与阅读时间
+func plainStats(text string) Stats {
+	return newStats(countWords(text))
+}
+
+func newStats(cjk, words int) Stats {
+	stats := Stats{Words: cjk + words}
+	if stats.Words > 0 {
+		minutes := float64(cjk)/cjkPerMinute + float64(words)/wordPerMinute
+		stats.ReadingTime = int(math.Max(1, math.Ceil(minutes)))
+	}
+	return stats
+}
+
+// countWords 分别统计中日韩文字的字数与其他语言的词数
+func countWords(text string) (cjk, words int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		case r == '\'' || r == '’' || r == '-' || r == '_':
+			// 单词内的撇号、连字符不拆分单词
+		default:
+			inWord = false
+		}
+	}
+	return cjk, words
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// newArticleMeta 汇总文章的统计信息与更新时间
+func newArticleMeta(stats Stats, modTime time.Time, fm utils.FrontMatter) ArticleMeta {
+	meta := ArticleMeta{Stats: stats, ModTime: modTime}
+	if t, ok := utils.ParseDate(fm.Updated); ok {
+		meta.Updated = &t
+	}
+	return meta
+}
+
+// articleModTime 文章的最后修改时间，优先取索引库中记录的时间，其次取文件修改时间
+func articleModTime(file string) time.Time {
+	if indexer != nil {
+		if doc, ok := indexer.Find(file); ok && doc != nil {
+			return doc.ModTime
+		}
+	}
+	if finfo, err := os.Stat(file); err == nil {
+		return finfo.ModTime()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/russross/blackfriday/v2"
+)
+
+// summaryLength 摘要的最大字数，超出部分以省略号代替
+const summaryLength = 120
+
+// briefCache 文件路径 -> 文章摘要与 front matter，文件修改后重新读取
+var briefCache sync.Map
+
+// articleBrief 列表页面中显示的文章信息
+type articleBrief struct {
+	modTime     time.Time
+	Summary     string
+	FrontMatter utils.FrontMatter
+}
+
+// readBrief 读取文章的摘要与 front matter，摘要为 front matter 中的 summary，未设置时取正文的第一段
+func readBrief(file string) articleBrief {
+	finfo, err := os.Stat(file)
+	if err != nil {
+		return articleBrief{}
+	}
+	if v, ok := briefCache.Load(file); ok && v.(articleBrief).modTime.Equal(finfo.ModTime()) {
+		return v.(articleBrief)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return articleBrief{}
+	}
+	src, ok := readSource(file, content)
+	if !ok {
+		return articleBrief{}
+	}
+	text := strings.TrimSpace(src.FrontMatter.Summary)
+	if text == "" {
+		text = firstParagraph(src.Markdown)
+	}
+	if text == "" && src.Markdown == "" {
+		text = strings.Join(strings.Fields(src.Text), " ")
+	}
+	brief := articleBrief{modTime: finfo.ModTime(), Summary: truncateText(text, summaryLength), FrontMatter: src.FrontMatter}
+	briefCache.Store(file, brief)
+	return brief
+}
+
+// articleSummary 文章摘要
+func articleSummary(file string) string {
+	return readBrief(file).Summary
+}
+
+// firstParagraph 返回 Markdown 中第一个段落的纯文本，不含标题、代码块、表格与 HTML
+func firstParagraph(markdown string) string {
+	if markdown == "" {
+		return ""
+	}
+	md := blackfriday.New(blackfriday.WithExtensions(markdownExtensions))
+	for node := md.Parse([]byte(markdown)).FirstChild; node != nil; node = node.Next {
+		if node.Type != blackfriday.Paragraph {
+			continue
+		}
+		var b strings.Builder
+		node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+			if entering && (n.Type == blackfriday.Text || n.Type == blackfriday.Code) {
+				b.Write(n.Literal)
+			}
+			if entering && n.Type == blackfriday.Softbreak {
+				b.WriteByte(' ')
+			}
+			return blackfriday.GoToNext
+		})
+		if text := strings.Join(strings.Fields(b.String()), " "); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// truncateText 截取前 n 个字符，超出时末尾加省略号
+func truncateText(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return strings.TrimSpace(string([]rune(text)[:n])) + "…"
+}
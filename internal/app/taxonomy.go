@@ -0,0 +1,247 @@
+package app
+
+import (
+	"math"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/kataras/iris/v12"
+)
+
+// PageSize 标签、分类等列表页面每页显示的文章数
+var PageSize = 20
+
+// 标签与分类页面的路由前缀
+var taxonomyRoutes = map[string]string{
+	tagKind:      "/tags",
+	categoryKind: "/categories",
+}
+
+// 标签与分类页面的标题
+var taxonomyTitles = map[string]string{
+	tagKind:      "标签",
+	categoryKind: "分类",
+}
+
+// TagLink 文章的标签或分类
+type TagLink struct {
+	Name string `json:"name"`
+	Link string `json:"link"`
+}
+
+// TagCount 标签云中的标签及其文章数量
+type TagCount struct {
+	TagLink
+	Count int `json:"count"`
+	Size  int `json:"-"` // 标签云中的字号等级 1-5
+}
+
+// PostEntry 列表页面中的一篇文章
+type PostEntry struct {
+	Title      string    `json:"title"`
+	Link       string    `json:"link"`
+	Date       time.Time `json:"date"`    // front matter 中的日期，未设置时为索引中记录的修改时间
+	Updated    time.Time `json:"updated"` // front matter 中的 updated 与修改时间中较晚的一个
+	Summary    string    `json:"summary"`
+	Tags       []TagLink `json:"tags"`
+	Categories []TagLink `json:"categories"`
+
+	file string
+}
+
+// Pager 列表页面的分页
+type Pager struct {
+	Page      int `json:"page"`
+	PageCount int `json:"pageCount"`
+	Total     int `json:"total"`
+	Prev      int `json:"prev"` // 上一页的页码，没有时为 0
+	Next      int `json:"next"` // 下一页的页码，没有时为 0
+}
+
+// tagLinks 将标签或分类的名称转换为链接，名称中的 / 作为路径分隔符保留，如 c/c++ 对应 /tags/c/c++
+func tagLinks(kind string, names []string) []TagLink {
+	links := make([]TagLink, 0, len(names))
+	for _, name := range names {
+		segments := strings.Split(name, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		links = append(links, TagLink{Name: name, Link: taxonomyRoutes[kind] + "/" + strings.Join(segments, "/")})
+	}
+	return links
+}
+
+// pageNode 返回文章文件在导航中对应的节点，子目录的 index 与 README 对应目录，不存在时返回 false
+func pageNode(wiki *wikiIndex, file string) (*utils.Node, bool) {
+	key := pathKey(file)
+	if dir := path.Dir(key); dir != "." && utils.IsDirIndex(filepath.Base(file)) {
+		key = dir
+	}
+	node, ok := wiki.byKey[strings.ToLower(key)]
+	return node, ok
+}
+
+// postEntries 将文章文件转换为列表条目，按日期从新到旧排列。
+// 草稿与已删除或不在导航中的文章不显示
+func postEntries(files []string) []PostEntry {
+	wiki := getWiki()
+	posts := make([]PostEntry, 0, len(files))
+	seen := make(map[*utils.Node]bool)
+	for _, file := range files {
+		node, ok := pageNode(wiki, file)
+		if !ok || seen[node] {
+			continue
+		}
+		seen[node] = true
+		brief := readBrief(file)
+		if brief.FrontMatter.Draft {
+			continue
+		}
+		posts = append(posts, PostEntry{
+			Title:      node.ShowName,
+			Link:       node.Link,
+			Date:       postDate(file, brief.FrontMatter),
+			Updated:    postUpdated(file, brief.FrontMatter),
+			Summary:    brief.Summary,
+			Tags:       tagLinks(tagKind, brief.FrontMatter.Tags),
+			Categories: tagLinks(categoryKind, brief.FrontMatter.Categories),
+			file:       file,
+		})
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		if !posts[i].Date.Equal(posts[j].Date) {
+			return posts[i].Date.After(posts[j].Date)
+		}
+		return posts[i].Title < posts[j].Title
+	})
+	return posts
+}
+
+// paginate 返回第 page 页的分页信息与条目范围，页码超出范围时取最近的一页
+func paginate(total, page int) (Pager, int, int) {
+	size := PageSize
+	if size <= 0 {
+		size = total
+	}
+	pager := Pager{Page: page, PageCount: 1, Total: total}
+	if size > 0 {
+		pager.PageCount = (total + size - 1) / size
+	}
+	if pager.PageCount < 1 {
+		pager.PageCount = 1
+	}
+	if pager.Page < 1 {
+		pager.Page = 1
+	}
+	if pager.Page > pager.PageCount {
+		pager.Page = pager.PageCount
+	}
+	if pager.Page > 1 {
+		pager.Prev = pager.Page - 1
+	}
+	if pager.Page < pager.PageCount {
+		pager.Next = pager.Page + 1
+	}
+	start := (pager.Page - 1) * size
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return pager, start, end
+}
+
+// taxonomy 返回某类标签的全部标签及其文章数量，按名称排列，名称不区分大小写
+func taxonomy(kind string) []TagCount {
+	if indexer == nil {
+		return nil
+	}
+	wiki := getWiki()
+	counts := make(map[string]*TagCount)
+	var names []string
+	all := indexer.Tags(kind)
+	keys := make([]string, 0, len(all))
+	for name := range all {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		files := all[name]
+		count := 0
+		for _, file := range files {
+			if _, ok := pageNode(wiki, file); ok && !readBrief(file).FrontMatter.Draft {
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		key := strings.ToLower(name)
+		if tag, ok := counts[key]; ok {
+			tag.Count += count
+			continue
+		}
+		counts[key] = &TagCount{TagLink: tagLinks(kind, []string{name})[0], Count: count}
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	tags := make([]TagCount, 0, len(names))
+	min, max := math.MaxInt32, 0
+	for _, key := range names {
+		tags = append(tags, *counts[key])
+		if c := counts[key].Count; c < min {
+			min = c
+		}
+		if c := counts[key].Count; c > max {
+			max = c
+		}
+	}
+	// 字号按数量的对数分为 5 级
+	for i := range tags {
+		tags[i].Size = 3
+		if max > min {
+			scale := math.Log(float64(tags[i].Count)/float64(min)) / math.Log(float64(max)/float64(min))
+			tags[i].Size = 1 + int(math.Round(scale*4))
+		}
+	}
+	return tags
+}
+
+// taxonomyHandler 标签云或全部分类的页面
+func taxonomyHandler(kind string) iris.Handler {
+	return func(ctx iris.Context) {
+		ctx.ViewData("Title", Title)
+		ctx.ViewData("ArticleTitle", taxonomyTitles[kind])
+		ctx.ViewData("Tags", taxonomy(kind))
+		ctx.View("tags.html")
+	}
+}
+
+// taxonomyListHandler 列出带有某个标签或分类的文章，按日期从新到旧分页显示
+func taxonomyListHandler(kind string) iris.Handler {
+	return func(ctx iris.Context) {
+		name := ctx.Params().Get("name")
+		var files []string
+		if indexer != nil {
+			files = indexer.Tagged(kind, name)
+		}
+		posts := postEntries(files)
+		if len(posts) == 0 {
+			ctx.StatusCode(404)
+			return
+		}
+		pager, start, end := paginate(len(posts), ctx.URLParamIntDefault("page", 1))
+		ctx.ViewData("Title", Title)
+		ctx.ViewData("ArticleTitle", taxonomyTitles[kind]+"："+name)
+		ctx.ViewData("Posts", posts[start:end])
+		ctx.ViewData("Pager", pager)
+		ctx.ViewData("PagePath", ctx.Path())
+		ctx.ViewData("FeedQuery", feedQuery(kind, name))
+		ctx.View("posts.html")
+	}
+}
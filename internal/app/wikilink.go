@@ -0,0 +1,198 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+	"github.com/russross/blackfriday/v2"
+)
+
+// 匹配 [[Page]]、[[Page#Heading]]、[[Page|alias]]、[[Page#Heading|alias]]
+var wikiLinkRegexp = regexp.MustCompile(`\[\[([^\[\]|#\n]*)(?:#([^\[\]|\n]*))?(?:\|([^\[\]\n]*))?\]\]`)
+
+// WikiLink 文章中的一个 [[...]] 链接
+type WikiLink struct {
+	Target  string // 目标页面名称或路径
+	Heading string // 目标标题，可为空
+	Alias   string // 显示文本，可为空
+}
+
+// Text 链接的显示文本
+func (l WikiLink) Text() string {
+	if l.Alias != "" {
+		return l.Alias
+	}
+	if l.Heading == "" {
+		return l.Target
+	}
+	if l.Target == "" {
+		return l.Heading
+	}
+	return l.Target + " > " + l.Heading
+}
+
+// parseWikiLinks 提取 Markdown 中非代码部分的全部 wiki 链接
+func parseWikiLinks(src string) []WikiLink {
+	var links []WikiLink
+	transformMarkdown(src, func(text string) string {
+		eachWikiLink(text, func(l WikiLink) string {
+			links = append(links, l)
+			return ""
+		})
+		return text
+	})
+	return links
+}
+
+// eachWikiLink 用 fn 的返回值替换 text 中的每个 wiki 链接，
+// 以 ! 开头的嵌入语法 ![[...]] 保持原样
+func eachWikiLink(text string, fn func(WikiLink) string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range wikiLinkRegexp.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > 0 && text[m[0]-1] == '!' {
+			continue
+		}
+		l := WikiLink{Target: strings.TrimSpace(text[m[2]:m[3]])}
+		if m[4] >= 0 {
+			l.Heading = strings.TrimSpace(text[m[4]:m[5]])
+		}
+		if m[6] >= 0 {
+			l.Alias = strings.TrimSpace(text[m[6]:m[7]])
+		}
+		out.WriteString(text[last:m[0]])
+		out.WriteString(fn(l))
+		last = m[1]
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// wikiIndex 按 utils.Explorer 生成的目录树解析 wiki 链接
+type wikiIndex struct {
+	byName  map[string][]*utils.Node // 小写的标题或文件名 -> 节点
+	byKey   map[string]*utils.Node   // 小写的访问路径 -> 节点
+	version string                   // 全部页面名称与路径的摘要，页面增删改名后变化
+}
+
+func newWikiIndex(tree utils.Node) *wikiIndex {
+	w := &wikiIndex{
+		byName: make(map[string][]*utils.Node),
+		byKey:  make(map[string]*utils.Node),
+	}
+	var walk func(node *utils.Node)
+	walk = func(node *utils.Node) {
+		for _, child := range node.Children {
+			if child.IsDir {
+				walk(child)
+				// 有页面的目录同样可以作为 wiki 链接的目标
+				if !child.Index {
+					continue
+				}
+			} else if !child.IsPage() {
+				continue
+			}
+			w.byKey[strings.ToLower(nodeKey(child))] = child
+			seen := make(map[string]bool)
+			base := child.Name
+			if !child.IsDir {
+				base = strings.TrimSuffix(base, path.Ext(base))
+			}
+			for _, name := range []string{child.ShowName, base, utils.NameTitle(child.Name)} {
+				name = strings.ToLower(name)
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				w.byName[name] = append(w.byName[name], child)
+			}
+		}
+	}
+	for _, root := range tree.Children {
+		walk(root)
+	}
+
+	pages := make([]string, 0, len(w.byKey))
+	for key, node := range w.byKey {
+		pages = append(pages, key+"|"+node.ShowName)
+	}
+	sort.Strings(pages)
+	w.version = utils.MD5(strings.Join(pages, "\n"))
+	return w
+}
+
+// nodeKey 节点的访问路径，与 getActiveNav 的返回值一致，如 ops/deploy
+func nodeKey(node *utils.Node) string {
+	link, _ := url.PathUnescape(node.Link)
+	return strings.TrimPrefix(link, "/")
+}
+
+// resolve 解析 wiki 链接目标，from 为当前文章的访问路径。
+// 同名页面优先选择与当前文章同目录的一篇
+func (w *wikiIndex) resolve(target, from string) *utils.Node {
+	target = trimFormatExt(strings.TrimSpace(target))
+	if target == "" {
+		return nil
+	}
+	if strings.Contains(target, "/") {
+		candidates := []string{strings.TrimPrefix(path.Clean("/"+target), "/")}
+		if !strings.HasPrefix(target, "/") {
+			candidates = append([]string{path.Join(path.Dir(from), target)}, candidates...)
+		}
+		for _, key := range candidates {
+			if node, ok := w.byKey[strings.ToLower(key)]; ok {
+				return node
+			}
+		}
+		return nil
+	}
+	nodes := w.byName[strings.ToLower(target)]
+	if len(nodes) == 0 {
+		return nil
+	}
+	dir := path.Dir(from)
+	for _, node := range nodes {
+		if path.Dir(nodeKey(node)) == dir {
+			return node
+		}
+	}
+	return nodes[0]
+}
+
+// destination 链接目标的访问路径，未找到的页面按名称生成路径
+func (w *wikiIndex) destination(l WikiLink, from string) (string, bool) {
+	if l.Target == "" {
+		return from, true
+	}
+	if node := w.resolve(l.Target, from); node != nil {
+		return nodeKey(node), true
+	}
+	return strings.TrimPrefix(path.Clean("/"+trimFormatExt(l.Target)), "/"), false
+}
+
+// renderWikiLinks 将 wiki 链接替换为 HTML 链接，未找到的页面使用 wikilink-broken 样式
+func (w *wikiIndex) renderWikiLinks(src, from string) string {
+	return transformMarkdown(src, func(text string) string {
+		return eachWikiLink(text, func(l WikiLink) string {
+			key, ok := w.destination(l, from)
+			href := "/" + utils.CustomURLEncode(key)
+			if l.Target == "" {
+				href = ""
+			}
+			if l.Heading != "" {
+				href += "#" + blackfriday.SanitizedAnchorName(l.Heading)
+			}
+			class := "wikilink"
+			if !ok {
+				class += " wikilink-broken"
+			}
+			return fmt.Sprintf(`<a class="%s" href="%s">%s</a>`, class, html.EscapeString(href), html.EscapeString(l.Text()))
+		})
+	})
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gaowei-space/markdown-blog/internal/utils"
+)
+
+// testWikiIndex 生成测试用的目录树：
+// setup、guide/setup（标题为 Setup Guide）、guide/intro、notes/setup、ops（有目录页面）与 ops/deploy
+func testWikiIndex() *wikiIndex {
+	page := func(name, showName, link string) *utils.Node {
+		return &utils.Node{Name: name, ShowName: showName, Link: link}
+	}
+	return newWikiIndex(utils.Node{Children: []*utils.Node{{
+		IsDir: true,
+		Children: []*utils.Node{
+			page("setup.md", "setup", "/setup"),
+			{Name: "guide", ShowName: "guide", Link: "/guide", IsDir: true, Children: []*utils.Node{
+				page("setup.md", "Setup Guide", "/guide/setup"),
+				page("01@intro.md", "intro", "/guide/01@intro"),
+			}},
+			{Name: "notes", ShowName: "notes", Link: "/notes", IsDir: true, Children: []*utils.Node{
+				page("setup.org", "setup", "/notes/setup"),
+				{ShowName: "Links", Separator: true},
+			}},
+			{Name: "ops", ShowName: "Operations", Link: "/ops", IsDir: true, Index: true, Children: []*utils.Node{
+				page("deploy.md", "deploy", "/ops/deploy"),
+			}},
+		},
+	}}})
+}
+
+func TestParseWikiLinks(t *testing.T) {
+	src := "[[Setup]] [[guide/setup#Install|install]] ![[embed]]\n" +
+		"`[[code]]`\n\n```\n[[fenced]]\n```\n\n    [[indented]]\n\n[[#Local]]\n"
+	want := []WikiLink{
+		{Target: "Setup"},
+		{Target: "guide/setup", Heading: "Install", Alias: "install"},
+		{Heading: "Local"},
+	}
+	if got := parseWikiLinks(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWikiLinks = %+v, want %+v", got, want)
+	}
+}
+
+func TestWikiLinkText(t *testing.T) {
+	tests := []struct {
+		link WikiLink
+		want string
+	}{
+		{WikiLink{Target: "a"}, "a"},
+		{WikiLink{Target: "a", Heading: "h"}, "a > h"},
+		{WikiLink{Heading: "h"}, "h"},
+		{WikiLink{Target: "a", Heading: "h", Alias: "x"}, "x"},
+	}
+	for _, tt := range tests {
+		if got := tt.link.Text(); got != tt.want {
+			t.Errorf("%+v.Text() = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestWikiResolve(t *testing.T) {
+	w := testWikiIndex()
+	tests := []struct {
+		target, from, want string
+	}{
+		{"setup", "index", "setup"},
+		{"setup", "guide/intro", "guide/setup"},
+		{"setup", "notes/other", "notes/setup"},
+		{"Setup Guide", "index", "guide/setup"},
+		{"setup.md", "guide/intro", "guide/setup"},
+		{"intro", "index", "guide/01@intro"},
+		{"setup", "ops/deploy", "setup"},
+		{"operations", "index", "ops"},
+		{"ops", "index", "ops"},
+		{"deploy", "index", "ops/deploy"},
+		{"../setup", "guide/intro", "setup"},
+		{"/guide/setup.md", "notes/a", "guide/setup"},
+		{"setup.org", "notes/a", "notes/setup"},
+		{"ops/deploy", "guide/intro", "ops/deploy"},
+		{"GUIDE/SETUP", "index", "guide/setup"},
+		{"guide", "index", ""},
+		{"links", "index", ""},
+		{"missing", "index", ""},
+		{"guide/missing", "index", ""},
+		{"  ", "index", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if node := w.resolve(tt.target, tt.from); node != nil {
+			got = nodeKey(node)
+		}
+		if got != tt.want {
+			t.Errorf("resolve(%q, %q) = %q, want %q", tt.target, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestWikiDestination(t *testing.T) {
+	w := testWikiIndex()
+	tests := []struct {
+		link   WikiLink
+		from   string
+		want   string
+		wantOK bool
+	}{
+		{WikiLink{Target: "setup"}, "guide/intro", "guide/setup", true},
+		{WikiLink{Heading: "Local"}, "guide/intro", "guide/intro", true},
+		{WikiLink{Target: "New Page"}, "guide/intro", "New Page", false},
+		{WikiLink{Target: "drafts/idea.md"}, "guide/intro", "drafts/idea", false},
+		{WikiLink{Target: "../../etc/passwd"}, "guide/intro", "etc/passwd", false},
+	}
+	for _, tt := range tests {
+		got, ok := w.destination(tt.link, tt.from)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("destination(%+v, %q) = %q, %v, want %q, %v", tt.link, tt.from, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRenderWikiLinks(t *testing.T) {
+	w := testWikiIndex()
+	tests := []struct {
+		src, want string
+	}{
+		{"[[setup]]", `<a class="wikilink" href="/setup">setup</a>`},
+		{"[[文档]]", `<a class="wikilink wikilink-broken" href="/%E6%96%87%E6%A1%A3">文档</a>`},
+		{"[[Setup Guide#Install Steps|steps]]", `<a class="wikilink" href="/guide/setup#install-steps">steps</a>`},
+		{"[[#Top]]", `<a class="wikilink" href="#top">Top</a>`},
+		{"[[New <Page>]]", `<a class="wikilink wikilink-broken" href="/New &lt;Page&gt;">New &lt;Page&gt;</a>`},
+		{"`[[setup]]`", "`[[setup]]`"},
+		{"![[setup]]", "![[setup]]"},
+	}
+	for _, tt := range tests {
+		if got := w.renderWikiLinks(tt.src, "index"); got != tt.want {
+			t.Errorf("renderWikiLinks(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// 页面增删或改名后版本变化
+func TestWikiIndexVersion(t *testing.T) {
+	a, b := testWikiIndex(), testWikiIndex()
+	if a.version != b.version {
+		t.Errorf("version of the same tree differs: %q, %q", a.version, b.version)
+	}
+	c := newWikiIndex(utils.Node{Children: []*utils.Node{{IsDir: true, Children: []*utils.Node{
+		{Name: "setup.md", ShowName: "setup", Link: "/setup"},
+	}}}})
+	if c.version == a.version {
+		t.Error("version of a different tree is unchanged")
+	}
+}
@@ -0,0 +1,12 @@
+package types
+
+// 参考文献
+type Bibliography struct {
+	File      string   `json:"file"`      // 全站使用的 BibTeX 文件，相对路径基于 Markdown 目录
+	Overrides []string `json:"overrides"` // 按目录指定 BibTeX 文件，格式 dir:file
+}
+
+func (b *Bibliography) SetBibliography(file string, overrides []string) {
+	b.File = file
+	b.Overrides = overrides
+}
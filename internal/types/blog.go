@@ -0,0 +1,12 @@
+package types
+
+// 博客模式
+type Blog struct {
+	Enable   bool     `json:"enable"`    // 首页显示按日期排列的文章列表
+	PostDirs []string `json:"post_dirs"` // 作为博客文章的目录，相对路径基于 Markdown 目录，为空时为全部文章
+}
+
+func (b *Blog) SetBlog(enable bool, postDirs []string) {
+	b.Enable = enable
+	b.PostDirs = postDirs
+}
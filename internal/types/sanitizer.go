@@ -0,0 +1,28 @@
+package types
+
+// HTML 清理策略
+type Sanitizer struct {
+	Preset    string   `json:"preset"`     // 预设策略 strict|ugc|trusted
+	Elements  []string `json:"elements"`   // 额外允许的标签
+	Attrs     []string `json:"attrs"`      // 额外允许的属性，格式 attr1,attr2:tag1,tag2，省略标签时全局允许
+	IframeSrc []string `json:"iframe_src"` // 允许嵌入的 iframe 地址前缀
+	Overrides []string `json:"overrides"`  // 按目录覆盖预设策略，格式 dir:preset
+
+	ShortcodeElements  []string `json:"shortcode_elements"`   // 短代码输出中额外允许的标签
+	ShortcodeAttrs     []string `json:"shortcode_attrs"`      // 短代码输出中额外允许的属性
+	ShortcodeIframeSrc []string `json:"shortcode_iframe_src"` // 短代码输出中允许嵌入的 iframe 地址前缀
+}
+
+func (s *Sanitizer) SetSanitizer(preset string, elements []string, attrs []string, iframeSrc []string, overrides []string) {
+	s.Preset = preset
+	s.Elements = elements
+	s.Attrs = attrs
+	s.IframeSrc = iframeSrc
+	s.Overrides = overrides
+}
+
+func (s *Sanitizer) SetShortcode(elements []string, attrs []string, iframeSrc []string) {
+	s.ShortcodeElements = elements
+	s.ShortcodeAttrs = attrs
+	s.ShortcodeIframeSrc = iframeSrc
+}
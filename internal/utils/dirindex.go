@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// dirIndexNames 作为目录页面的文件名（不含后缀），依次优先
+var dirIndexNames = []string{"index", "readme"}
+
+// IsDirIndex 判断文件名是否可以作为目录页面，如 index.md、README.md
+func IsDirIndex(name string) bool {
+	base := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+	for _, index := range dirIndexNames {
+		if base == index {
+			return IsArticle(name)
+		}
+	}
+	return false
+}
+
+// DirIndex 返回目录页面的文件路径：index 优先，其次为 README，同名时按格式的优先顺序，不存在时返回空
+func DirIndex(dir string) string {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, index := range dirIndexNames {
+		found := ""
+		for _, f := range files {
+			name := f.Name()
+			if !f.IsDir() && IsDirIndex(name) && strings.EqualFold(strings.TrimSuffix(name, path.Ext(name)), index) {
+				if found == "" || formatRank(name) < formatRank(found) {
+					found = name
+				}
+			}
+		}
+		if found != "" {
+			return path.Join(dir, found)
+		}
+	}
+	return ""
+}
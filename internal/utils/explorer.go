@@ -6,18 +6,30 @@ import (
 	"os"
 	"path"
 	"strings"
-	"math/rand"
+	"time"
 )
 
 // Node 树节点
 type Node struct {
-	Name     string  `json:"name"`     // 目录或文件名
-	ShowName string  `json:"showName"` // 目录或文件名（不包含后缀）
-	Path     string  `json:"path"`     // 目录或文件完整路径
-	Link     string  `json:"link"`     // 文件访问URI
-	Active   string  `json:"active"`   // 当前活跃的文件
-	Children []*Node `json:"children"` // 目录下的文件或子目录
-	IsDir    bool    `json:"isDir"`    // 是否为目录 true: 是目录 false: 不是目录
+	Name      string    `json:"name"`      // 目录或文件名
+	ShowName  string    `json:"showName"`  // 目录或文件名（不包含后缀）
+	Path      string    `json:"path"`      // 目录或文件完整路径
+	Link      string    `json:"link"`      // 文件访问URI
+	Active    string    `json:"active"`    // 当前活跃的文件
+	Children  []*Node   `json:"children"`  // 目录下的文件或子目录
+	IsDir     bool      `json:"isDir"`     // 是否为目录 true: 是目录 false: 不是目录
+	Weight    int       `json:"weight"`    // front matter 中的排序权重
+	Date      time.Time `json:"date"`      // front matter 中的日期，未设置时为文件修改时间
+	More      int       `json:"more"`      // 导航中未显示的子节点数量
+	Hidden    bool      `json:"hidden"`    // 不在导航中显示，仍可访问
+	Separator bool      `json:"separator"` // 导航中的分隔标题，ShowName 为空时为分隔线
+	External  bool      `json:"external"`  // 导航中的外部链接
+	Index     bool      `json:"index"`     // 目录中有 index.md 或 README.md 作为目录页面
+}
+
+// IsPage 是否为文章，目录、分隔标题与外部链接不是文章
+func (n *Node) IsPage() bool {
+	return !n.IsDir && !n.Separator && !n.External
 }
 
 // Option 遍历选项
@@ -26,6 +38,9 @@ type Option struct {
 	SubFlag          bool     `yaml:"subFlag"`          // 遍历子目录标志 true: 遍历 false: 不遍历
 	IgnorePath       []string `yaml:"ignorePath"`       // 忽略目录
 	IgnoreFile       []string `yaml:"ignoreFile"`       // 忽略文件
+	Sort             string   `yaml:"sort"`             // 文件的排序方式：name、title、date 或 weight
+
+	summary map[string]*navMeta // 根目录中 SUMMARY.md 定义的各目录的导航配置
 }
 
 // 当前再循环的Dir路径
@@ -51,6 +66,7 @@ func Explorer(option Option) (Node, error) {
 		// 目录路径
 		CurDirPath = p
 		child.Path = p
+		option.summary = loadSummary(p)
 
 		// 递归
 		explorerRecursive(&child, &option)
@@ -90,6 +106,12 @@ func explorerRecursive(node *Node, option *Option) {
 
 	var mdFiles []*Node
 
+	// 子目录的页面文件由目录节点代表，不单独显示，根目录的 index 仍作为文章显示
+	index := ""
+	if node.Path != CurDirPath {
+		index = DirIndex(node.Path)
+	}
+
 	for _, f := range sub {
 		tmp := path.Join(node.Path, f.Name())
 		var child Node
@@ -101,10 +123,7 @@ func explorerRecursive(node *Node, option *Option) {
 		child.Link = CustomURLEncode(strings.TrimPrefix(strings.TrimSuffix(tmp, path.Ext(f.Name())), CurDirPath))
 
 		// 目录或文件名（不包含后缀）
-		child.ShowName = strings.TrimSuffix(f.Name(), path.Ext(f.Name()))
-		if strings.Index(child.ShowName, "@") != -1 {
-			child.ShowName = child.ShowName[strings.Index(child.ShowName, "@")+1:]
-		}
+		child.ShowName = NameTitle(f.Name())
 		// 是否为目录
 		child.IsDir = f.IsDir()
 
@@ -116,30 +135,85 @@ func explorerRecursive(node *Node, option *Option) {
 				if !IsInSlice(option.IgnorePath, f.Name()) {
 					node.Children = append(node.Children, &child)
 					explorerRecursive(&child, option)
+					// 目录页面的标题作为目录的标题
+					if file := DirIndex(tmp); file != "" {
+						child.Index = true
+						if title := FileTitle(file); title != NameTitle(path.Base(file)) {
+							child.ShowName = title
+						}
+					}
 				}
 			}
 		} else { // 文件
-			// 过滤非md文件
-			if path.Ext(f.Name()) != ".md" {
+			// 过滤未启用格式的文件
+			if !IsArticle(f.Name()) {
 				continue
 			}
 
 			// 非忽略文件，添加到结果中
-			if IsInSlice(option.IgnoreFile, f.Name()) {
+			if IsInSlice(option.IgnoreFile, f.Name()) || (index != "" && strings.TrimSuffix(tmp, path.Ext(tmp)) == strings.TrimSuffix(index, path.Ext(index))) {
 				continue
 			}
 
+			// 文章标题与排序信息
+			meta := fileMeta(tmp, f.ModTime())
+			child.ShowName = meta.title
+			child.Weight = meta.weight
+			child.Date = meta.date
+
 			mdFiles = append(mdFiles, &child)
 		}
 	}
 
-	// 超过指定数量的.md文件进行随机展示
-	if len(mdFiles) > 150 {
-		rand.Shuffle(len(mdFiles), func(i, j int) {
-			mdFiles[i], mdFiles[j] = mdFiles[j], mdFiles[i]
-		})
-		node.Children = append(node.Children, mdFiles[:150]...)
-	} else {
-		node.Children = append(node.Children, mdFiles...)
+	// 子目录在前并按名称排列，文件按指定方式排序
+	mdFiles = uniqueArticles(mdFiles)
+	SortNodes(mdFiles, option.Sort)
+	node.Children = append(node.Children, mdFiles...)
+
+	// 按 _meta.yml 或 SUMMARY.md 调整顺序、标题与隐藏的条目
+	rel := strings.Trim(strings.TrimPrefix(node.Path, CurDirPath), "/")
+	if meta := dirNavMeta(node.Path, rel, option.summary); meta != nil {
+		meta.apply(node)
+	}
+}
+
+// Rebuild 重新遍历 dir 目录，返回新的目录树。只复制从根到该目录路径上的节点，
+// 其余节点与原树共用，原树不会被修改。dir 已不存在时重新遍历最近的上级目录
+func Rebuild(tree Node, dir string, option Option) Node {
+	for i, root := range tree.Children {
+		if dir != root.Path && !strings.HasPrefix(dir, root.Path+"/") {
+			continue
+		}
+		for dir != root.Path {
+			if finfo, err := os.Stat(dir); err == nil && finfo.IsDir() {
+				break
+			}
+			dir = path.Dir(dir)
+		}
+		CurDirPath = root.Path
+		option.summary = loadSummary(root.Path)
+		children := append([]*Node(nil), tree.Children...)
+		children[i] = rebuildNode(root, dir, &option)
+		tree.Children = children
+		break
+	}
+	return tree
+}
+
+// rebuildNode 复制 node 并重新遍历其中的 dir 目录，dir 不在树中时重新遍历最近的上级目录
+func rebuildNode(node *Node, dir string, option *Option) *Node {
+	copied := *node
+	if node.Path != dir {
+		for i, child := range node.Children {
+			if child.IsDir && (dir == child.Path || strings.HasPrefix(dir, child.Path+"/")) {
+				copied.Children = append([]*Node(nil), node.Children...)
+				copied.Children[i] = rebuildNode(child, dir, option)
+				return &copied
+			}
+		}
 	}
+	// 目录自身的标题与隐藏由上级目录的配置决定，保持不变
+	copied.Children = nil
+	explorerRecursive(&copied, option)
+	return &copied
 }
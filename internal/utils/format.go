@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"path"
+)
+
+// formatTitles 已启用的文章格式：后缀 -> 从内容中解析标题的函数，由 app 根据配置设置
+var formatTitles = map[string]func(content []byte, name string) string{
+	".md": ContentTitle,
+}
+
+// formatExts 已启用格式的后缀，按优先顺序排列
+var formatExts = []string{".md"}
+
+// SetFormats 设置导航中显示的文章格式，titles 为后缀 -> 解析标题的函数，函数为空时以文件名为标题，
+// exts 为按优先顺序排列的后缀
+func SetFormats(titles map[string]func(content []byte, name string) string, exts []string) {
+	formatTitles = titles
+	formatExts = exts
+}
+
+// formatRank 文件格式的优先顺序，越小越优先
+func formatRank(name string) int {
+	ext := path.Ext(name)
+	for i, e := range formatExts {
+		if e == ext {
+			return i
+		}
+	}
+	return len(formatExts)
+}
+
+// uniqueArticles 同一路径有多种格式的文章时只保留优先的格式，与路由查找文章的顺序一致
+func uniqueArticles(nodes []*Node) []*Node {
+	kept := make(map[string]int)
+	var out []*Node
+	for _, n := range nodes {
+		if i, ok := kept[n.Link]; ok {
+			if formatRank(n.Name) < formatRank(out[i].Name) {
+				out[i] = n
+			}
+			continue
+		}
+		kept[n.Link] = len(out)
+		out = append(out, n)
+	}
+	return out
+}
+
+// IsArticle 判断文件是否为已启用格式的文章
+func IsArticle(name string) bool {
+	_, ok := formatTitles[path.Ext(name)]
+	return ok
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter 文章开头 --- 包裹的 YAML 元数据
+type FrontMatter struct {
+	Title   string `yaml:"title"`   // 标题
+	Updated string `yaml:"updated"` // 最后更新日期
+	Date    string `yaml:"date"`    // 发布日期
+	Weight  int    `yaml:"weight"`  // 导航中的排序权重，越小越靠前
+	Summary string `yaml:"summary"` // 摘要，未设置时取正文的第一段
+	Draft   bool   `yaml:"draft"`   // 草稿，不出现在 sitemap 与文章列表中
+
+	Tags       StringList `yaml:"tags"`       // 标签
+	Categories StringList `yaml:"categories"` // 分类
+}
+
+// StringList front matter 中的字符串列表，也可以写成以逗号分隔的字符串，如 tags: go, web
+type StringList []string
+
+// UnmarshalYAML 解析列表或以逗号分隔的字符串，去掉空项与重复项
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	var items []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		items = strings.Split(value.Value, ",")
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind == yaml.ScalarNode {
+				items = append(items, item.Value)
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	*l = nil
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if key := strings.ToLower(item); item != "" && !seen[key] {
+			seen[key] = true
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// 支持的日期格式
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// ParseFrontMatter 解析文章开头的 front matter，返回元数据与去掉 front matter 后的正文。
+// 没有 front matter 或 YAML 无法解析时原样返回正文
+func ParseFrontMatter(content []byte) (FrontMatter, []byte) {
+	var fm FrontMatter
+	text := bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(text, []byte("---\n")) && !bytes.HasPrefix(text, []byte("---\r\n")) {
+		return fm, content
+	}
+	rest := text[bytes.IndexByte(text, '\n')+1:]
+	end, next := -1, 0
+	for pos := 0; pos < len(rest); {
+		line := rest[pos:]
+		n := bytes.IndexByte(line, '\n')
+		if n >= 0 {
+			line = line[:n+1]
+		}
+		if trimmed := strings.TrimSpace(string(line)); trimmed == "---" || trimmed == "..." {
+			end, next = pos, pos+len(line)
+			break
+		}
+		pos += len(line)
+	}
+	if end < 0 {
+		return fm, content
+	}
+
+	var data map[string]interface{}
+	if err := yaml.Unmarshal(rest[:end], &data); err != nil || data == nil {
+		return fm, content
+	}
+	yaml.Unmarshal(rest[:end], &fm)
+	return fm, rest[next:]
+}
+
+// ParseDate 解析 front matter 中的日期
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SummaryFile = "SUMMARY.md" // 根目录中 GitBook 风格的导航目录
+	MetaFile    = "_meta.yml"  // 目录中的导航配置
+)
+
+var (
+	// 匹配 SUMMARY.md 中的列表项，如 * [标题](path.md)
+	summaryItemRegexp = regexp.MustCompile(`^([ \t]*)[*+-][ \t]+\[([^\]]*)\]\(([^)]*)\)`)
+	// 匹配 SUMMARY.md 中作为分隔标题的二级及以下标题
+	summaryHeadingRegexp = regexp.MustCompile(`^#{2,6}[ \t]+(.+?)[ \t#]*$`)
+	// 匹配 SUMMARY.md 中的分隔线
+	summaryRuleRegexp = regexp.MustCompile(`^[ \t]*(-[ \t]*){3,}$|^[ \t]*(\*[ \t]*){3,}$`)
+)
+
+// NavEntry 导航配置中的一项：目录中的文件或子目录、分隔标题或外部链接
+type NavEntry struct {
+	Name      string `yaml:"name"`   // 文件名（可不含后缀）或子目录名
+	Title     string `yaml:"title"`  // 显示的标题，为空时使用原标题
+	Hidden    bool   `yaml:"hidden"` // 不在导航中显示，仍可访问
+	Separator bool   `yaml:"-"`      // 分隔标题，Title 为空时为分隔线
+	Link      string `yaml:"link"`   // 外部链接
+}
+
+// navMeta 一个目录的导航配置
+type navMeta struct {
+	entries      []NavEntry
+	hideUnlisted bool // 未列出的条目不显示，SUMMARY.md 按 GitBook 的规则只显示列出的条目
+}
+
+// UnmarshalYAML 条目可以写成文件名、"---" 分隔线，或包含 name、title、hidden、separator、link 的映射
+func (e *NavEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if strings.TrimSpace(value.Value) == "---" {
+			e.Separator = true
+		} else {
+			e.Name = value.Value
+		}
+		return nil
+	}
+	type plain NavEntry
+	var entry struct {
+		plain     `yaml:",inline"`
+		Separator yaml.Node `yaml:"separator"`
+	}
+	if err := value.Decode(&entry); err != nil {
+		return err
+	}
+	*e = NavEntry(entry.plain)
+	if entry.Separator.Kind != 0 {
+		e.Separator = true
+		if entry.Separator.Tag == "!!str" {
+			e.Title = entry.Separator.Value
+		}
+	}
+	return nil
+}
+
+// dirNavMeta 返回目录的导航配置：优先使用目录中的 _meta.yml，其次为 SUMMARY.md 中的配置
+func dirNavMeta(dir, rel string, summary map[string]*navMeta) *navMeta {
+	if content, err := os.ReadFile(path.Join(dir, MetaFile)); err == nil {
+		var entries []NavEntry
+		if err := yaml.Unmarshal(content, &entries); err != nil {
+			log.Printf("Invalid %s: %v", path.Join(dir, MetaFile), err)
+		} else {
+			return &navMeta{entries: entries}
+		}
+	}
+	return summary[rel]
+}
+
+// apply 按配置调整目录的子节点：依次排列列出的条目并设置标题与隐藏，
+// 插入分隔标题与外部链接，未列出的条目按原顺序排在最后
+func (m *navMeta) apply(node *Node) {
+	used := make(map[*Node]bool)
+	children := make([]*Node, 0, len(node.Children)+len(m.entries))
+	for _, e := range m.entries {
+		switch {
+		case e.Separator:
+			children = append(children, &Node{ShowName: e.Title, Separator: true})
+		case e.Link != "":
+			children = append(children, &Node{ShowName: e.Title, Link: e.Link, External: true})
+		default:
+			for _, child := range node.Children {
+				if used[child] || !matchEntry(child, e.Name) {
+					continue
+				}
+				used[child] = true
+				if e.Title != "" {
+					child.ShowName = e.Title
+				}
+				child.Hidden = e.Hidden
+				children = append(children, child)
+				break
+			}
+		}
+	}
+	for _, child := range node.Children {
+		if !used[child] {
+			child.Hidden = m.hideUnlisted
+			children = append(children, child)
+		}
+	}
+	node.Children = children
+}
+
+// matchEntry 判断节点是否为配置中的条目，文件可以省略后缀
+func matchEntry(node *Node, name string) bool {
+	name = strings.Trim(strings.TrimSpace(name), "/")
+	return name != "" && (node.Name == name || (!node.IsDir && strings.TrimSuffix(node.Name, path.Ext(node.Name)) == name))
+}
+
+// loadSummary 解析根目录中的 SUMMARY.md，转换为各目录的导航配置，不存在时返回 nil
+func loadSummary(root string) map[string]*navMeta {
+	content, err := os.ReadFile(path.Join(root, SummaryFile))
+	if err != nil {
+		return nil
+	}
+	summary, err := parseSummary(content)
+	if err != nil {
+		log.Printf("Invalid %s: %v", SummaryFile, err)
+		return nil
+	}
+	return summary
+}
+
+// parseSummary 解析 GitBook 风格的 SUMMARY.md。列表项按出现顺序确定所在目录中条目的顺序，
+// 链接文字作为标题，指向 README.md 或 index.md 的链接作为目录的标题；
+// 二级及以下标题与分隔线作为根目录中的分隔，外部链接放在上级条目对应的目录中
+func parseSummary(content []byte) (map[string]*navMeta, error) {
+	summary := make(map[string]*navMeta)
+	add := func(dir string, e NavEntry) {
+		meta, ok := summary[dir]
+		if !ok {
+			meta = &navMeta{hideUnlisted: true}
+			summary[dir] = meta
+		}
+		if e.Name != "" {
+			for i, old := range meta.entries {
+				if old.Name == e.Name {
+					if e.Title != "" {
+						meta.entries[i].Title = e.Title
+					}
+					return
+				}
+			}
+		}
+		meta.entries = append(meta.entries, e)
+	}
+
+	type level struct {
+		indent int
+		dir    string // 下一层级中外部链接所在的目录：目录条目为该目录，文件条目为文件所在目录
+	}
+	var stack []level
+	summary[""] = &navMeta{hideUnlisted: true}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if m := summaryHeadingRegexp.FindStringSubmatch(line); m != nil {
+			add("", NavEntry{Title: m[1], Separator: true})
+			stack = stack[:0]
+			continue
+		}
+		if summaryRuleRegexp.MatchString(line) {
+			add("", NavEntry{Separator: true})
+			continue
+		}
+		m := summaryItemRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := ""
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].dir
+		}
+		title, target := strings.TrimSpace(m[2]), strings.TrimSpace(m[3])
+
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+			add(parent, NavEntry{Title: title, Link: target})
+			stack = append(stack, level{indent: indent, dir: parent})
+			continue
+		}
+		file := summaryPath(target)
+		if file == "" {
+			// 尚未编写的章节，如 [草稿]()
+			stack = append(stack, level{indent: indent, dir: parent})
+			continue
+		}
+
+		// 指向 README.md 或 index.md 的条目代表所在目录
+		dir, name := path.Dir(file), path.Base(file)
+		self := dir
+		if base := strings.ToLower(strings.TrimSuffix(name, path.Ext(name))); (base == "readme" || base == "index") && dir != "." {
+			dir, name = path.Dir(dir), path.Base(dir)
+		}
+		if dir == "." {
+			dir = ""
+		}
+		if self == "." {
+			self = ""
+		}
+		add(dir, NavEntry{Name: name, Title: title})
+		// 上级目录中依次列出所在的目录
+		for d := dir; d != ""; d = parentDir(d) {
+			add(parentDir(d), NavEntry{Name: path.Base(d)})
+		}
+		stack = append(stack, level{indent: indent, dir: self})
+	}
+	return summary, scanner.Err()
+}
+
+// summaryPath 将 SUMMARY.md 中的链接转换为相对根目录的文件路径，去掉锚点，无效时返回空
+func summaryPath(target string) string {
+	target, _, _ = strings.Cut(target, "#")
+	if file, err := url.PathUnescape(target); err == nil {
+		target = file
+	}
+	return strings.TrimPrefix(path.Clean("/"+target), "/")
+}
+
+// parentDir 返回相对路径的上级目录，根目录为空
+func parentDir(dir string) string {
+	if parent := path.Dir(dir); parent != "." {
+		return parent
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NotebookExt Jupyter Notebook 文件的后缀
+const NotebookExt = ".ipynb"
+
+// 匹配终端输出中的 ANSI 颜色等控制序列
+var ansiRegexp = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// Notebook Jupyter Notebook 文件，仅支持 nbformat 4
+type Notebook struct {
+	NBFormat int            `json:"nbformat"`
+	Cells    []NotebookCell `json:"cells"`
+	Metadata struct {
+		Title      string `json:"title"`
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// NotebookCell Notebook 中的一个单元格
+type NotebookCell struct {
+	CellType    string                             `json:"cell_type"` // markdown、code 或 raw
+	Source      NotebookText                       `json:"source"`
+	Outputs     []NotebookOutput                   `json:"outputs"`     // 代码单元格保存的输出
+	Attachments map[string]map[string]NotebookText `json:"attachments"` // markdown 单元格的附件：文件名 -> MIME 类型 -> base64 内容
+}
+
+// NotebookOutput 代码单元格的一项输出
+type NotebookOutput struct {
+	OutputType string                  `json:"output_type"` // stream、execute_result、display_data 或 error
+	Name       string                  `json:"name"`        // stream 输出的 stdout 或 stderr
+	Text       NotebookText            `json:"text"`        // stream 输出的文本
+	Data       map[string]NotebookText `json:"data"`        // MIME 类型 -> 内容
+	EName      string                  `json:"ename"`
+	EValue     string                  `json:"evalue"`
+	Traceback  []string                `json:"traceback"`
+}
+
+// NotebookText 以字符串或字符串数组保存的多行文本，其他 JSON 值保留原文
+type NotebookText string
+
+func (t *NotebookText) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = NotebookText(s)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err == nil {
+		*t = NotebookText(strings.Join(lines, ""))
+		return nil
+	}
+	*t = NotebookText(b)
+	return nil
+}
+
+// ParseNotebook 解析 Notebook 文件内容
+func ParseNotebook(content []byte) (*Notebook, error) {
+	var nb Notebook
+	if err := json.Unmarshal(content, &nb); err != nil {
+		return nil, err
+	}
+	if nb.NBFormat < 4 {
+		return nil, fmt.Errorf("unsupported nbformat %d", nb.NBFormat)
+	}
+	return &nb, nil
+}
+
+// Language 代码单元格的编程语言
+func (nb *Notebook) Language() string {
+	if nb.Metadata.LanguageInfo.Name != "" {
+		return strings.ToLower(nb.Metadata.LanguageInfo.Name)
+	}
+	if nb.Metadata.KernelSpec.Language != "" {
+		return strings.ToLower(nb.Metadata.KernelSpec.Language)
+	}
+	return "python"
+}
+
+// Markdown 所有 markdown 单元格的内容，用于解析标题、链接与统计字数
+func (nb *Notebook) Markdown() string {
+	var parts []string
+	for _, cell := range nb.Cells {
+		if cell.CellType == "markdown" {
+			parts = append(parts, strings.TrimSpace(string(cell.Source)))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Text 可搜索的文本，包括 markdown 单元格、代码以及文本形式的输出
+func (nb *Notebook) Text() string {
+	var parts []string
+	for _, cell := range nb.Cells {
+		if cell.CellType == "raw" {
+			continue
+		}
+		parts = append(parts, strings.TrimSpace(string(cell.Source)))
+		for _, o := range cell.Outputs {
+			switch {
+			case o.OutputType == "stream":
+				parts = append(parts, strings.TrimSpace(string(o.Text)))
+			case o.OutputType == "error":
+				parts = append(parts, o.EName+": "+o.EValue)
+			case o.Data["text/plain"] != "":
+				parts = append(parts, strings.TrimSpace(string(o.Data["text/plain"])))
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// StripANSI 去掉终端输出中的控制序列
+func StripANSI(s string) string {
+	return ansiRegexp.ReplaceAllString(s, "")
+}
+
+// NotebookTitle Notebook 的标题，依次取元数据中的 title、第一个一级标题、文件名
+func NotebookTitle(content []byte, name string) string {
+	nb, err := ParseNotebook(content)
+	if err != nil {
+		return NameTitle(name)
+	}
+	if title := strings.TrimSpace(nb.Metadata.Title); title != "" {
+		return title
+	}
+	return ContentTitle([]byte(nb.Markdown()), name)
+}
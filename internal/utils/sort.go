@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// 导航中文件的排序方式
+const (
+	SortByName   = "name"   // 按文件名，可用 01@ 等前缀调整顺序
+	SortByTitle  = "title"  // 按标题
+	SortByDate   = "date"   // 按日期，最新的在前
+	SortByWeight = "weight" // 按 front matter 中的 weight，未设置的排在最后
+)
+
+// IsSortMode 判断是否为支持的排序方式
+func IsSortMode(mode string) bool {
+	switch mode {
+	case SortByName, SortByTitle, SortByDate, SortByWeight:
+		return true
+	}
+	return false
+}
+
+// SortNodes 按指定方式排序，相同时按文件名排序，结果是确定的
+func SortNodes(nodes []*Node, mode string) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		switch mode {
+		case SortByTitle:
+			if ta, tb := strings.ToLower(a.ShowName), strings.ToLower(b.ShowName); ta != tb {
+				return ta < tb
+			}
+		case SortByDate:
+			if !a.Date.Equal(b.Date) {
+				return a.Date.After(b.Date)
+			}
+		case SortByWeight:
+			if a.Weight != b.Weight {
+				if a.Weight == 0 || b.Weight == 0 {
+					return b.Weight == 0
+				}
+				return a.Weight < b.Weight
+			}
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 匹配一级标题，如 # Title 或 # Title #
+var h1Regexp = regexp.MustCompile(`^ {0,3}#[ \t]+(.+?)(?:[ \t]+#+)?[ \t]*$`)
+
+// 匹配 Setext 形式一级标题的下划线 ===
+var setextH1Regexp = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+
+// 匹配 [text](url) 形式的链接
+var inlineLinkRegexp = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+
+// metaCache 文件路径 -> 标题等信息，文件修改后重新读取
+var metaCache sync.Map
+
+// cachedMeta 导航所需的文章信息
+type cachedMeta struct {
+	modTime time.Time
+	title   string
+	weight  int
+	date    time.Time
+}
+
+// FileTitle 文章标题，按文章的格式解析，如 Markdown 依次取 front matter 中的 title、第一个一级标题、文件名
+func FileTitle(file string) string {
+	finfo, err := os.Stat(file)
+	if err != nil {
+		return NameTitle(path.Base(file))
+	}
+	return fileMeta(file, finfo.ModTime()).title
+}
+
+// fileMeta 读取文章的标题、排序权重与日期，日期未设置时取文件修改时间
+func fileMeta(file string, modTime time.Time) cachedMeta {
+	if v, ok := metaCache.Load(file); ok && v.(cachedMeta).modTime.Equal(modTime) {
+		return v.(cachedMeta)
+	}
+	meta := cachedMeta{modTime: modTime, title: NameTitle(path.Base(file)), date: modTime}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return meta
+	}
+	if parse := formatTitles[path.Ext(file)]; parse != nil {
+		meta.title = parse(content, path.Base(file))
+	}
+	fm, _ := ParseFrontMatter(content)
+	meta.weight = fm.Weight
+	if date, ok := ParseDate(fm.Date); ok {
+		meta.date = date
+	}
+	metaCache.Store(file, meta)
+	return meta
+}
+
+// ContentTitle 从文章内容中解析标题，name 为文件名
+func ContentTitle(content []byte, name string) string {
+	fm, body := ParseFrontMatter(content)
+	if title := strings.TrimSpace(fm.Title); title != "" {
+		return title
+	}
+	if title := FirstHeading(string(body)); title != "" {
+		return title
+	}
+	return NameTitle(name)
+}
+
+// FirstHeading 返回 Markdown 中第一个一级标题的文本，跳过代码块
+func FirstHeading(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			for len(fence) < len(trimmed) && trimmed[len(fence)] == fence[0] {
+				fence = trimmed[:len(fence)+1]
+			}
+			continue
+		}
+		if m := h1Regexp.FindStringSubmatch(line); m != nil {
+			return plainText(m[1])
+		}
+		if trimmed != "" && !strings.HasPrefix(line, "    ") && i+1 < len(lines) && setextH1Regexp.MatchString(lines[i+1]) {
+			return plainText(trimmed)
+		}
+	}
+	return ""
+}
+
+// NameTitle 由文件名得到标题：去掉后缀以及 @ 之前的排序前缀
+func NameTitle(name string) string {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if i := strings.Index(name, "@"); i != -1 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// plainText 去掉标题中的链接与强调等行内标记
+func plainText(s string) string {
+	s = inlineLinkRegexp.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer("**", "", "__", "", "`", "").Replace(s)
+	return strings.TrimSpace(s)
+}
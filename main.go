@@ -133,7 +133,22 @@ func flags() []cli.Flag {
 			Name:    "cache",
 			Aliases: []string{"c"},
 			Value:   3,
-			Usage:   "The cache time unit is minutes, this parameter takes effect in the prod environment",
+			Usage:   "Deprecated and has no effect: pages are cached by render-cache, use static-cache for images and files",
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:  "static-cache",
+			Value: 3,
+			Usage: "The cache time of images and files for browsers, unit is minutes, this parameter takes effect in the prod environment",
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:  "render-cache",
+			Value: 500,
+			Usage: "The number of rendered articles cached in memory, 0 disables the cache",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "render-cache-dir",
+			Value: "",
+			Usage: "Persist rendered articles to `DIR`, default is empty",
 		}),
 		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:  "icp",
@@ -154,6 +169,40 @@ func flags() []cli.Flag {
 			Value: "public",
 			Usage: "File directory name",
 		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "source-dir",
+			Value: "",
+			Usage: "Source code `DIR` that articles can embed into code blocks, default is empty",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "formats",
+			Value: cli.NewStringSlice("md", "ipynb", "csv", "tsv"),
+			Usage: "Enabled article formats by file extension (md, ipynb, org, txt, html, csv, tsv), earlier formats take precedence for the same path",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "nav-sort",
+			Value: "name",
+			Usage: "Sort articles in the navigation by name, title, date or weight",
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:  "nav-page-size",
+			Value: 150,
+			Usage: "Number of items shown per directory in the navigation before \"show more\", 0 shows all",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "base-url",
+			Value: "",
+			Usage: "Site URL used in sitemap.xml and robots.txt, eg: https://example.com, default is the requested host",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "robots.disallow",
+			Usage: "Paths that robots.txt disallows crawling, also excluded from sitemap.xml, eg: /drafts",
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:  "page-size",
+			Value: 20,
+			Usage: "Number of articles per page on tag, category and other listing pages, 0 shows all",
+		}),
 	}
 
 	gitalkFlags := []cli.Flag{
@@ -214,5 +263,87 @@ func flags() []cli.Flag {
 	}
 
 	flags = append(flags, ignoreFlags...)
+
+	sanitizerFlags := []cli.Flag{
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "sanitizer.preset",
+			Value: "ugc",
+			Usage: "HTML sanitizer preset, strict|ugc|trusted",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "sanitizer.elements",
+			Usage: "Set up extra allowed HTML elements, eg: kbd",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "sanitizer.attrs",
+			Usage: "Set up extra allowed HTML attributes, eg: controls,src:video",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "sanitizer.iframe-src",
+			Usage: "Set up allowed iframe source prefixes, eg: https://www.youtube.com/embed/",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "sanitizer.overrides",
+			Usage: "Set up sanitizer preset per directory, eg: internal:trusted",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "sanitizer.shortcode-elements",
+			Usage: "Set up extra allowed HTML elements in shortcode output, eg: track",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "sanitizer.shortcode-attrs",
+			Usage: "Set up extra allowed HTML attributes in shortcode output, eg: style:span",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "sanitizer.shortcode-iframe-src",
+			Value: cli.NewStringSlice("https://www.youtube.com/embed/", "https://www.youtube-nocookie.com/embed/", "https://player.bilibili.com/", "https://player.vimeo.com/video/"),
+			Usage: "Set up allowed iframe source prefixes in shortcode output",
+		}),
+	}
+
+	flags = append(flags, sanitizerFlags...)
+
+	bibliographyFlags := []cli.Flag{
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:  "bibliography.file",
+			Value: "",
+			Usage: "BibTeX `FILE` for [@key] citations, relative to the markdown dir, default is empty",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "bibliography.overrides",
+			Usage: "Set up BibTeX file per directory, eg: papers:papers/refs.bib",
+		}),
+	}
+
+	flags = append(flags, bibliographyFlags...)
+
+	blogFlags := []cli.Flag{
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:  "blog.enable",
+			Value: false,
+			Usage: "Show the latest posts on the home page instead of the first article",
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:  "blog.post-dirs",
+			Usage: "Directories whose articles are blog posts, relative to the markdown dir, default is all articles, eg: posts",
+		}),
+	}
+
+	flags = append(flags, blogFlags...)
+
+	feedFlags := []cli.Flag{
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:  "feed.limit",
+			Value: 20,
+			Usage: "Number of recently created or updated articles in the RSS, Atom and JSON feeds, 0 includes all",
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:  "feed.full",
+			Value: false,
+			Usage: "Include the full rendered article in feeds instead of the summary",
+		}),
+	}
+
+	flags = append(flags, feedFlags...)
 	return flags
 }
@@ -8,9 +8,10 @@
             // adding the trigger element to each ARTICLES parent and binding the event
             var chapterLink = $(ARTICLES).parent(CHAPTER).children('a');
             chapterLink.append($(TRIGGER_TEMPLATE));
-            chapterLink.on('click', function (e) {
+            // 点击目录名称打开目录页面，点击箭头展开或折叠
+            chapterLink.on('click', '.exc-trigger', function (e) {
                 e.preventDefault();
-                //e.stopPropagation();
+                e.stopPropagation();
                 toggle($(e.target).closest(CHAPTER));
             });
 
@@ -14,6 +14,111 @@
         changeTheme(false)
     });
 
+    // 文章大纲：高亮当前阅读的标题
+    var $outlineLinks = $('.article-outline a');
+    if ($outlineLinks.length) {
+        var $headings = $('.markdown-body').find('h1,h2,h3,h4,h5,h6').filter('[id]');
+        var $scroller = $('.body-inner');
+        var onScroll = function () {
+            var current = null;
+            $headings.each(function () {
+                if (this.getBoundingClientRect().top <= 80) {
+                    current = this.id;
+                }
+            });
+            $outlineLinks.each(function () {
+                $(this).parent().toggleClass('active', decodeURIComponent(this.hash.slice(1)) === current);
+            });
+        };
+        $scroller.on('scroll', onScroll);
+        $(window).on('scroll', onScroll);
+        onScroll();
+    }
+
+    // 脚注与参考文献：鼠标悬停时预览内容
+    var $preview = $('<div class="footnote-preview"></div>').hide().appendTo('body');
+    $('.markdown-body').on('mouseenter', 'sup.footnote-ref a, a.citation-ref', function () {
+        var target = document.getElementById(decodeURIComponent(this.hash.slice(1)));
+        if (!target) {
+            return;
+        }
+        var $content = $(target).clone();
+        $content.find('.footnote-return').remove();
+        var rect = this.getBoundingClientRect();
+        $preview.html($content.html()).css({
+            left: Math.max(8, Math.min(rect.left, window.innerWidth - 420)),
+            top: rect.bottom + 6
+        }).show();
+    }).on('mouseleave', 'sup.footnote-ref a, a.citation-ref', function () {
+        $preview.hide();
+    });
+
+    // 标签页短代码：根据各面板的 data-title 生成切换按钮
+    $('.markdown-body .tabs').each(function () {
+        var $panels = $(this).children('.tab-panel');
+        var $nav = $('<div class="tabs-nav"></div>').prependTo(this);
+        $panels.each(function (i) {
+            $('<button type="button"></button>').text($(this).data('title') || i + 1).appendTo($nav);
+        });
+        $nav.on('click', 'button', function () {
+            var i = $(this).index();
+            $(this).addClass('active').siblings().removeClass('active');
+            $panels.removeClass('active').eq(i).addClass('active');
+        });
+        $nav.children().first().click();
+    });
+
+    // 数据表格：点击表头按该列排序，再次点击切换升降序，数值列按数值排序
+    $('.markdown-body table.data-table').each(function () {
+        var $table = $(this);
+        $table.find('thead th').attr('tabindex', 0).on('click keydown', function (e) {
+            if (e.type === 'keydown' && e.key !== 'Enter') return;
+            var col = $(this).index();
+            var asc = $(this).attr('aria-sort') !== 'ascending';
+            $(this).attr('aria-sort', asc ? 'ascending' : 'descending').siblings().removeAttr('aria-sort');
+
+            var $tbody = $table.children('tbody');
+            var rows = $tbody.children('tr').get().map(function (tr) {
+                var text = $.trim($(tr.cells[col]).text());
+                return { tr: tr, text: text, num: text === '' ? NaN : Number(text.replace(/[,_%$¥€\s]/g, '')) };
+            });
+            var numeric = rows.every(function (r) { return r.text === '' || !isNaN(r.num); });
+            rows.sort(function (a, b) {
+                var x = numeric ? (isNaN(a.num) ? -Infinity : a.num) : a.text;
+                var y = numeric ? (isNaN(b.num) ? -Infinity : b.num) : b.text;
+                var d = numeric ? (x < y ? -1 : x > y ? 1 : 0) : x.localeCompare(y);
+                return asc ? d : -d;
+            });
+            $tbody.append(rows.map(function (r) { return r.tr; }));
+        });
+    });
+
+    // 导航中的“显示更多”：按页加载目录中其余的条目，链接本身指向目录页面
+    $('.book-summary').on('click', '.nav-more > a', function (e) {
+        e.preventDefault();
+        var $more = $(this).parent();
+        if ($more.hasClass('loading')) return;
+        $more.addClass('loading');
+        $.getJSON($(this).data('api'), { offset: $more.prevAll('li').length }, function (page) {
+            $.each(page.items, function (_, item) {
+                if (item.separator) {
+                    $(item.showName ? '<li class="header"></li>' : '<li class="divider"></li>').text(item.showName).insertBefore($more);
+                    return;
+                }
+                var $a = $('<a></a>').attr('href', item.link).text(item.showName);
+                if (item.external) $a.attr({ target: '_blank', rel: 'noopener' });
+                $('<li class="chapter"></li>').append($a).insertBefore($more);
+            });
+            if (page.more > 0) {
+                $more.removeClass('loading').children('a').text('显示更多（' + page.more + '）');
+            } else {
+                $more.remove();
+            }
+        }).fail(function () {
+            $more.removeClass('loading');
+        });
+    });
+
     function changeTheme(isInit = false) {
         color = isInit ? getThemeState().color : (getThemeState().color == 'dark' ? 'white' : 'dark')
 